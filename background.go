@@ -1,142 +1,192 @@
-package main
+package chord
 
 import (
+	"context"
 	"crypto/sha1"
 	"fmt"
-	"log"
 	"math/big"
 	"time"
 )
 
 const (
-	maxSuccessors    = 5
 	numFingerEntries = 161
-
-	stabilizeInterval        = 1
-	fixFingersInterval       = 1
-	checkPredecessorInterval = 1
 )
 
-var next = 0 // The next entry in the finger table to fix
-
-// Run stabilize, fix fingers, and check predecessor in background goroutines
-func (n *Node) startBackgroundMaintenance() {
+// Run stabilize, fix fingers, check predecessor, anti-entropy, hint delivery, expiry, chunk collection, and state saving in background goroutines
+func (n *Node) startBackgroundMaintenance() error {
 	// Stabilize
-	if err := n.stabilize(); err != nil {
-		log.Fatalf("initial stabilize: %v", err)
+	if err := n.stabilize(n.ctx); err != nil {
+		return fmt.Errorf("initial stabilize: %v", err)
 	}
-	log.Printf("Stabilizing every %d seconds\n", stabilizeInterval)
-	go func() {
-		for range time.Tick(time.Second * stabilizeInterval) {
-			if err := n.stabilize(); err != nil {
-				log.Printf("stabilize: %v", err)
-			}
-		}
-	}()
+	n.logger.Printf("Stabilizing every %v\n", n.config.StabilizeInterval)
+	n.repeat(n.config.StabilizeInterval, "stabilize", n.stabilize)
 	// FixFingers
-	if err := n.fixFingers(); err != nil {
-		log.Fatalf("initial fix fingers: %v\n", err)
+	if err := n.fixFingers(n.ctx); err != nil {
+		return fmt.Errorf("initial fix fingers: %v", err)
 	}
-	log.Printf("Fixing fingers every %d seconds", fixFingersInterval)
-	go func() {
-		for range time.Tick(time.Second * fixFingersInterval) {
-			if err := n.fixFingers(); err != nil {
-				log.Printf("fix fingers: %v", err)
-			}
-		}
-	}()
+	n.logger.Printf("Fixing fingers every %v", n.config.FixFingersInterval)
+	n.repeat(n.config.FixFingersInterval, "fix fingers", n.fixFingers)
 	// CheckPredecessor
-	if err := n.checkPredecessor(); err != nil {
-		log.Fatalf("initial check predecessor: %v", err)
+	if err := n.checkPredecessor(n.ctx); err != nil {
+		return fmt.Errorf("initial check predecessor: %v", err)
 	}
-	log.Printf("Checking predecessor every %d seconds\n", checkPredecessorInterval)
+	n.logger.Printf("Checking predecessor every %v\n", n.config.CheckPredecessorInterval)
+	n.repeat(n.config.CheckPredecessorInterval, "check predecessor", n.checkPredecessor)
+	// AntiEntropy
+	n.logger.Printf("Comparing items with replicas every %v\n", n.config.AntiEntropyInterval)
+	n.repeat(n.config.AntiEntropyInterval, "anti-entropy", n.antiEntropy)
+	// DeliverHints
+	n.logger.Printf("Delivering hinted puts every %v\n", n.config.HintInterval)
+	n.repeat(n.config.HintInterval, "deliver hints", n.deliverHints)
+	// Expire
+	n.logger.Printf("Removing expired items every %v\n", n.config.ExpireInterval)
+	n.repeat(n.config.ExpireInterval, "expire", n.expire)
+	// CollectChunks
+	n.logger.Printf("Collecting unlisted chunks every %v\n", n.config.ExpireInterval)
+	n.repeat(n.config.ExpireInterval, "collect chunks", n.collectChunks)
+	// SaveState
+	if n.config.DataDir != "" {
+		if err := n.saveState(n.ctx); err != nil {
+			return fmt.Errorf("initial save state: %v", err)
+		}
+		n.logger.Printf("Saving successors to %s\n", n.config.DataDir)
+		n.repeat(n.config.StabilizeInterval, "save state", n.saveState)
+	}
+	return nil
+}
+
+// Run a task every interval in a background goroutine until the node leaves the ring
+func (n *Node) repeat(interval time.Duration, name string, task func(context.Context) error) {
+	ticker := time.NewTicker(interval)
 	go func() {
-		for range time.Tick(time.Second * checkPredecessorInterval) {
-			if err := n.checkPredecessor(); err != nil {
-				log.Printf("check predecessor: %v", err)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-n.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := task(n.ctx); err != nil {
+					n.logger.Printf("%s: %v", name, err)
+				}
 			}
 		}
 	}()
 }
 
 // Maintain successor list correctly
-func (n *Node) stabilize() error {
+func (n *Node) stabilize(ctx context.Context) error {
+	var successor Address
+	var replicas []Address
+	n.actor.run(func(n *Node) {
+		successor = n.Successors[0]
+		replicas = n.replicaTargets(n.config.Replicas - 1)
+	})
 	var links NodeLink
-	if err := call(n.Successors[0], "NodeActor.GetNodeLinks", None{}, &links); err != nil {
-		// Cut off that one from list
-		n.Successors = n.Successors[1:]
-		if len(n.Successors) == 0 {
-			// No successors so set successor to ourself
-			n.Successors = []Address{n.Address}
-		}
-		log.Printf("stabilize: sucessor failure, new successor is %s: %v\n", n.Successors[0], err)
-	} else {
-		// Update successor links
-
-		for i := 1; i < maxSuccessors; i++ {
-			if i >= len(n.Successors) || n.Successors[i] != links.Successors[i-1] {
-				log.Println("stabilize: successors list changed")
-				break
+	err := n.client.call(ctx, successor, "NodeActor.GetNodeLinks", None{}, &links)
+	changed := false
+	n.actor.run(func(n *Node) {
+		if err != nil {
+			// Cut off that one from list
+			n.Successors = n.Successors[1:]
+			if len(n.Successors) == 0 {
+				// No successors so set successor to ourself
+				n.Successors = []Address{n.Address}
+			}
+			n.logger.Printf("stabilize: sucessor failure, new successor is %s: %v\n", n.Successors[0], err)
+		} else {
+			// Update successor links
+
+			for i := 1; i < n.config.Successors; i++ {
+				if i >= len(n.Successors) || n.Successors[i] != links.Successors[i-1] {
+					n.logger.Println("stabilize: successors list changed")
+					break
+				}
 			}
-		}
 
-		// Add current node's first successor to successor's successor list
-		n.prependSuccessor(n.Successors[0], links.Successors)
+			// Add current node's first successor to successor's successor list
+			n.prependSuccessor(n.Successors[0], links.Successors)
 
-		// Update predecessor links
+			// Update predecessor links
 
-		// Check if our successor's predecessor should be our successor
-		if links.Predecessor != "" && between(n.Hash, links.Predecessor.hashed(), n.Successors[0].hashed(), false) {
-			// Set our successor to be this node in between now
-			n.prependSuccessor(links.Predecessor, n.Successors)
-			log.Printf("stabilize: better successor found: %s\n", n.Successors[0])
+			// Check if our successor's predecessor should be our successor
+			if links.Predecessor != "" && between(n.Hash, links.Predecessor.hashed(), n.Successors[0].hashed(), false) {
+				// Set our successor to be this node in between now
+				n.prependSuccessor(links.Predecessor, n.Successors)
+				n.logger.Printf("stabilize: better successor found: %s\n", n.Successors[0])
+			}
 		}
-	}
+		successor = n.Successors[0]
+		changed = !sameAddresses(replicas, n.replicaTargets(n.config.Replicas-1))
+	})
 	// Notify successor to check its predecessor
-	if err := call(n.Successors[0], "NodeActor.Notify", n.Address, &None{}); err != nil {
+	if err := n.client.call(ctx, successor, "NodeActor.Notify", n.Address, &None{}); err != nil {
 		return fmt.Errorf("notifying successor: %v", err)
 	}
 
+	// Copy owned items to any successors that just became replicas
+	if changed {
+		n.replicate(ctx)
+	}
+
 	return nil
 }
 
 // Refreshes finger table entries.
-func (n *Node) fixFingers() error {
-	next++
-	if next >= numFingerEntries {
-		next = 1
-	}
-	address, err := find(n.jump(next), n.Address)
+func (n *Node) fixFingers(ctx context.Context) error {
+	var next int
+	var id *big.Int
+	n.actor.run(func(n *Node) {
+		n.nextFinger++
+		if n.nextFinger >= numFingerEntries {
+			n.nextFinger = 1
+		}
+		next, id = n.nextFinger, n.jump(n.nextFinger)
+	})
+	ctx, cancel := context.WithTimeout(ctx, n.config.LookupTimeout)
+	defer cancel()
+	address, err := n.client.find(ctx, id, n.Address)
 	if err != nil {
 		return fmt.Errorf("finding finger table entry: %v", err)
 	}
-	changed := n.Fingers[next] == "" || (address != n.Fingers[next])
-	n.Fingers[next] = address
-	// Optimization because sparse nodes mean the successor for each entry is probably the same
-	if changed {
-		log.Printf("fixFingers: writing new entry %d as %s", next, address)
-	}
-	for next+1 < numFingerEntries && between(n.Hash, n.jump(next+1), address.hashed(), false) {
-		next++
+	n.actor.run(func(n *Node) {
+		changed := n.Fingers[next] == "" || (address != n.Fingers[next])
 		n.Fingers[next] = address
-	}
-	if changed {
-		log.Printf("fixFingers: repeated up to entry %d", next)
-	}
+		// Optimization because sparse nodes mean the successor for each entry is probably the same
+		if changed {
+			n.logger.Printf("fixFingers: writing new entry %d as %s", next, address)
+		}
+		for next+1 < numFingerEntries && between(n.Hash, n.jump(next+1), address.hashed(), false) {
+			next++
+			n.Fingers[next] = address
+		}
+		n.nextFinger = next
+		if changed {
+			n.logger.Printf("fixFingers: repeated up to entry %d", next)
+		}
+	})
 
 	return nil
 }
 
 // Verify predecessor is still functional
-func (n *Node) checkPredecessor() error {
-	if n.Predecessor == "" {
-		log.Println("checkPredecessor: no predecessor")
+func (n *Node) checkPredecessor(ctx context.Context) error {
+	var predecessor Address
+	n.actor.run(func(n *Node) {
+		predecessor = n.Predecessor
+	})
+	if predecessor == "" {
+		n.logger.Println("checkPredecessor: no predecessor")
 		return nil
 	}
 	var success bool
-	if err := call(n.Predecessor, "NodeActor.Ping", None{}, &success); err != nil || !success {
-		log.Printf("checkPredecessor: failed to contact predecessor: %v\n", err)
-		n.Predecessor = ""
+	if err := n.client.call(ctx, predecessor, "NodeActor.Ping", None{}, &success); err != nil || !success {
+		n.logger.Printf("checkPredecessor: failed to contact predecessor: %v\n", err)
+		n.actor.run(func(n *Node) {
+			// A new predecessor may have notified the node in the meantime
+			if n.Predecessor == predecessor {
+				n.Predecessor = ""
+			}
+		})
 	}
 	return nil
 }
@@ -148,7 +198,7 @@ var two = big.NewInt(2)
 var hashMod = new(big.Int).Exp(two, big.NewInt(keySize), nil)
 
 // This computes the hash of a position across the ring that should be pointed to by the given finger table entry (using 1-based numbering).
-func (n Node) jump(fingerentry int) *big.Int {
+func (n *Node) jump(fingerentry int) *big.Int {
 	fingerentryminus1 := big.NewInt(int64(fingerentry) - 1)
 	jump := new(big.Int).Exp(two, fingerentryminus1, nil)
 	sum := new(big.Int).Add(n.Hash, jump)
@@ -162,7 +212,20 @@ func (n *Node) prependSuccessor(first Address, rest []Address) {
 	// Prepend current successor to a slice of the successors
 	n.Successors = append([]Address{first}, rest...)
 	// Truncate if necessary
-	if len(n.Successors) > maxSuccessors {
-		n.Successors = n.Successors[:maxSuccessors]
+	if len(n.Successors) > n.config.Successors {
+		n.Successors = n.Successors[:n.config.Successors]
+	}
+}
+
+// Whether two address lists are identical
+func sameAddresses(a, b []Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
 	}
+	return true
 }
@@ -0,0 +1,189 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// Batched operations find the owner of every key, group the keys by owner, and send each owner
+// all of its keys in one call. Owners are called in parallel, and a failed call only fails its own keys.
+// Batched puts replace every version of their keys and are never hinted. A batch holding a reserved key is refused as a whole.
+
+// BatchError lists the keys of a batched operation that failed and why. Every other key succeeded.
+type BatchError struct {
+	Errors map[Key]error
+}
+
+func (e *BatchError) Error() string {
+	keys := []Key{}
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	if len(keys) == 1 {
+		return fmt.Sprintf("key %s failed: %v", string(keys[0]), e.Errors[keys[0]])
+	}
+	return fmt.Sprintf("%d keys failed, the first %s: %v", len(keys), string(keys[0]), e.Errors[keys[0]])
+}
+
+// MultiPut stores many key/value pairs with one call to each node responsible for some of them.
+// Each value replaces every version of its key. If some keys could not be stored a *BatchError lists them.
+func (c *Client) MultiPut(ctx context.Context, items map[Key]string, consistency Consistency) error {
+	values := make(map[Key][]byte)
+	for key, value := range items {
+		values[key] = []byte(value)
+	}
+	return c.MultiPutBytes(ctx, values, consistency)
+}
+
+// MultiPutBytes stores many binary values with one call to each node responsible for some of them.
+// Each value replaces every version of its key. If some keys could not be stored a *BatchError lists them.
+func (c *Client) MultiPutBytes(ctx context.Context, items map[Key][]byte, consistency Consistency) error {
+	for key := range items {
+		if err := checkKey(key); err != nil {
+			return err
+		}
+	}
+	return c.multiPut(ctx, items, consistency)
+}
+
+// Store many values in a batch, including reserved keys
+func (c *Client) multiPut(ctx context.Context, items map[Key][]byte, consistency Consistency) error {
+	keys := []Key{}
+	for key := range items {
+		keys = append(keys, key)
+	}
+	_, err := c.batch(ctx, keys, func(owner Address, keys []Key) (map[Key]Siblings, error) {
+		request := MultiPutRequest{make(map[Key][]byte), consistency}
+		for _, key := range keys {
+			request.Items[key] = items[key]
+		}
+		return nil, c.coordinate(ctx, owner, "NodeActor.MultiPut", request, &None{})
+	})
+	return err
+}
+
+// MultiGet retrieves the versions of many keys with one call to each node responsible for some of them.
+// Keys that are not stored are left out of the result. If some keys could not be read a *BatchError lists them.
+func (c *Client) MultiGet(ctx context.Context, keys []Key, consistency Consistency) (map[Key]Siblings, error) {
+	if err := checkKeys(keys); err != nil {
+		return nil, err
+	}
+	return c.multiGet(ctx, keys, consistency)
+}
+
+// Read many keys in a batch, including reserved keys
+func (c *Client) multiGet(ctx context.Context, keys []Key, consistency Consistency) (map[Key]Siblings, error) {
+	return c.batch(ctx, keys, func(owner Address, keys []Key) (map[Key]Siblings, error) {
+		items := make(map[Key]Siblings)
+		err := c.coordinate(ctx, owner, "NodeActor.MultiGet", MultiKeyRequest{keys, consistency}, &items)
+		return items, err
+	})
+}
+
+// MultiDelete removes many keys with one call to each node responsible for some of them, and returns the deleted values.
+// Keys that were not stored are left out of the result. If some keys could not be deleted a *BatchError lists them.
+func (c *Client) MultiDelete(ctx context.Context, keys []Key, consistency Consistency) (map[Key]string, error) {
+	if err := checkKeys(keys); err != nil {
+		return nil, err
+	}
+	return c.multiDelete(ctx, keys, consistency)
+}
+
+// Delete many keys in a batch, including reserved keys
+func (c *Client) multiDelete(ctx context.Context, keys []Key, consistency Consistency) (map[Key]string, error) {
+	deleted, err := c.batch(ctx, keys, func(owner Address, keys []Key) (map[Key]Siblings, error) {
+		items := make(map[Key]Siblings)
+		err := c.coordinate(ctx, owner, "NodeActor.MultiDelete", MultiKeyRequest{keys, consistency}, &items)
+		return items, err
+	})
+	values := make(map[Key]string)
+	for key, siblings := range deleted {
+		values[key] = siblings.String()
+	}
+	return values, err
+}
+
+// Refuse a batch if any of its keys is reserved
+func checkKeys(keys []Key) error {
+	for _, key := range keys {
+		if err := checkKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Group keys by owner and make one call per owner in parallel, merging the replies.
+// The error is a *BatchError with the keys whose owner could not be found or whose call failed.
+func (c *Client) batch(ctx context.Context, keys []Key, send func(owner Address, keys []Key) (map[Key]Siblings, error)) (map[Key]Siblings, error) {
+	groups, failed := c.groupByOwner(ctx, keys)
+	results := make(map[Key]Siblings)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for owner, keys := range groups {
+		wg.Add(1)
+		go func(owner Address, keys []Key) {
+			defer wg.Done()
+			reply, err := send(owner, keys)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				for _, key := range keys {
+					failed[key] = fmt.Errorf("at %s: %v", owner, err)
+				}
+				return
+			}
+			for key, siblings := range reply {
+				results[key] = siblings
+			}
+		}(owner, keys)
+	}
+	wg.Wait()
+	if len(failed) > 0 {
+		return results, &BatchError{failed}
+	}
+	return results, nil
+}
+
+// Find the owner of each key and group the keys by owner, along with the keys whose owner could not be found.
+// An owner's predecessor bounds the range it owns, so keys falling in a range already found need no lookup.
+func (c *Client) groupByOwner(ctx context.Context, keys []Key) (map[Address][]Key, map[Key]error) {
+	type ownerRange struct {
+		start *big.Int // The owner's predecessor, the range is (start, owner]
+		owner Address
+	}
+	ranges := []ownerRange{}
+	groups := make(map[Address][]Key)
+	failed := make(map[Key]error)
+	seen := make(map[Key]bool)
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		var owner Address
+		for _, r := range ranges {
+			if between(r.start, key.hashed(), r.owner.hashed(), true) {
+				owner = r.owner
+				break
+			}
+		}
+		if owner == "" {
+			var err error
+			if owner, err = c.Lookup(ctx, key); err != nil {
+				failed[key] = fmt.Errorf("finding owner: %v", err)
+				continue
+			}
+			var links NodeLink
+			if err := c.call(ctx, owner, "NodeActor.GetNodeLinks", None{}, &links); err == nil && links.Predecessor != "" {
+				ranges = append(ranges, ownerRange{links.Predecessor.hashed(), owner})
+			}
+		}
+		groups[owner] = append(groups[owner], key)
+	}
+	return groups, failed
+}
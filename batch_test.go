@@ -0,0 +1,117 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestGroupByOwner(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 5, nil)
+	client := testClient(t, network, nodes)
+	keys := []Key{}
+	for i := 0; i < 50; i++ {
+		keys = append(keys, Key(fmt.Sprint("key", i)))
+	}
+	groups, failed := client.groupByOwner(context.Background(), append(keys, keys[0]))
+	if len(failed) != 0 {
+		t.Fatalf("failed to group %v", failed)
+	}
+	grouped := 0
+	for owner, keys := range groups {
+		for _, key := range keys {
+			if expected := ownerOf(nodes, key)[0].Address; owner != expected {
+				t.Errorf("%s grouped under %s, expected %s", string(key), owner, expected)
+			}
+		}
+		grouped += len(keys)
+	}
+	if grouped != len(keys) {
+		t.Errorf("grouped %d keys, expected %d", grouped, len(keys))
+	}
+}
+
+func TestMultiKeyOperations(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 5, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	items := map[Key]string{}
+	keys := []Key{}
+	for i := 0; i < 50; i++ {
+		key := Key(fmt.Sprint("key", i))
+		items[key] = fmt.Sprint(i)
+		keys = append(keys, key)
+	}
+	if err := client.MultiPut(ctx, items, Consistency{}); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := client.MultiGet(ctx, append(keys, "missing"), Consistency{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(items) {
+		t.Errorf("read %d keys, expected %d", len(read), len(items))
+	}
+	for key, value := range items {
+		if siblings := read[key]; siblings.String() != value {
+			t.Errorf("%s: read %v, expected %q", string(key), siblings, value)
+		}
+	}
+
+	deleted, err := client.MultiDelete(ctx, append(keys[:10], "missing"), Consistency{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 10 {
+		t.Errorf("deleted %d keys, expected 10", len(deleted))
+	}
+	for _, key := range keys[:10] {
+		if deleted[key] != items[key] {
+			t.Errorf("%s: deleted %q, expected %q", string(key), deleted[key], items[key])
+		}
+		if _, err := client.Get(ctx, key); err != ErrNoSuchKey {
+			t.Errorf("%s after delete: %v", string(key), err)
+		}
+	}
+}
+
+func TestBatchPartialFailure(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	cutOff := nodes[1]
+	items := map[Key]string{}
+	for i := 0; i < 30; i++ {
+		items[Key(fmt.Sprint("key", i))] = fmt.Sprint(i)
+	}
+
+	// The node cut off from the others cannot reach a quorum of replicas for its keys
+	for _, n := range nodes {
+		if n != cutOff {
+			network.Partition(cutOff.Address, n.Address)
+		}
+	}
+	err := client.MultiPut(ctx, items, Consistency{W: 2})
+	batchErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("expected a batch error, got %v", err)
+	}
+	for key := range items {
+		owned := ownerOf(nodes, key)[0] == cutOff
+		if _, failed := batchErr.Errors[key]; failed != owned {
+			t.Errorf("%s failed: %v, owned by the node cut off: %v", string(key), failed, owned)
+		}
+	}
+	network.Heal()
+
+	for key, value := range items {
+		_, failed := batchErr.Errors[key]
+		if got, err := client.Get(ctx, key); !failed && (err != nil || got != value) {
+			t.Errorf("%s: got %q, %v", string(key), got, err)
+		}
+	}
+}
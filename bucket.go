@@ -0,0 +1,174 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Buckets are namespaces of keys. A key in a bucket is stored on the ring in the reserved namespace, under the bucket
+// name and the key joined by a NUL byte, so it is hashed and placed like any other key. Plain keys cannot be reserved,
+// so they never collide with keys in buckets, and bucket names cannot hold a NUL byte, so neither do keys of different
+// buckets. Every operation on keys works in a bucket through the ring key from Bucket.Key, the one kind of reserved key
+// clients accept.
+
+const (
+	bucketPrefix    = reservedPrefix + "bucket/" // Starts the ring key of every key in a bucket
+	bucketSeparator = "\x00"                     // Joins a bucket name and a key
+)
+
+type (
+	// Bucket is a namespace of keys on the ring
+	Bucket struct {
+		Name   string
+		client *Client
+	}
+
+	// BucketStats sums up the live keys of a bucket across the ring, not counting replicas
+	BucketStats struct {
+		Keys  int // How many keys are stored
+		Bytes int // The size of their values, counting every sibling
+	}
+)
+
+// Bucket returns the namespace of keys with a name. The name must not be empty or contain a NUL byte.
+func (c *Client) Bucket(name string) (*Bucket, error) {
+	if name == "" || strings.Contains(name, bucketSeparator) {
+		return nil, fmt.Errorf("bad bucket name %q", name)
+	}
+	return &Bucket{name, c}, nil
+}
+
+// Split a ring key into its bucket and the key within the bucket, if it is in one
+func splitBucket(key Key) (bucket string, name string, ok bool) {
+	if !strings.HasPrefix(string(key), bucketPrefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(string(key)[len(bucketPrefix):], bucketSeparator, 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// Key is the ring key a key of the bucket is stored under, for use with any Client method
+func (b *Bucket) Key(key string) Key {
+	return Key(b.prefix() + key)
+}
+
+// Starts the ring key of every key in the bucket
+func (b *Bucket) prefix() string {
+	return bucketPrefix + b.Name + bucketSeparator
+}
+
+// Put stores a key/value pair in the bucket using the ring's default consistency
+func (b *Bucket) Put(ctx context.Context, key string, value string) error {
+	return b.client.Put(ctx, b.Key(key), value)
+}
+
+// Get retrieves the value of a key in the bucket using the ring's default consistency
+func (b *Bucket) Get(ctx context.Context, key string) (string, error) {
+	return b.client.Get(ctx, b.Key(key))
+}
+
+// Delete removes a key from the bucket using the ring's default consistency and returns the deleted value
+func (b *Bucket) Delete(ctx context.Context, key string) (string, error) {
+	return b.client.Delete(ctx, b.Key(key))
+}
+
+// Keys lists up to limit keys of the bucket in the order of their hashes, starting after the cursor of an earlier page.
+// The pattern is matched against keys within the bucket and the keys are returned without the bucket name.
+func (b *Bucket) Keys(ctx context.Context, cursor string, pattern string, limit int) (ScanPage, error) {
+	page, err := b.client.scan(ctx, cursor, b.prefix(), pattern, limit)
+	for i, key := range page.Keys {
+		_, name, _ := splitBucket(key)
+		page.Keys[i] = Key(name)
+	}
+	return page, err
+}
+
+// Drop deletes every key of the bucket, a page of keys at a time with batched deletes, and returns how many were deleted.
+// Keys written while the bucket is being dropped may be left behind.
+func (b *Bucket) Drop(ctx context.Context) (int, error) {
+	deleted := 0
+	cursor := ""
+	for {
+		page, err := b.client.scan(ctx, cursor, b.prefix(), "", 0)
+		if err != nil {
+			return deleted, err
+		}
+		if len(page.Keys) > 0 {
+			values, err := b.client.MultiDelete(ctx, page.Keys, Consistency{})
+			deleted += len(values)
+			if err != nil {
+				return deleted, err
+			}
+		}
+		if cursor = page.Cursor; cursor == "" {
+			return deleted, nil
+		}
+	}
+}
+
+// Stats sums up the keys of the bucket across the ring
+func (b *Bucket) Stats(ctx context.Context) (BucketStats, error) {
+	stats, err := b.client.bucketStats(ctx, b.Name)
+	return stats[b.Name], err
+}
+
+// Buckets returns the stats of every bucket holding keys, by name
+func (c *Client) Buckets(ctx context.Context) (map[string]BucketStats, error) {
+	return c.bucketStats(ctx, "")
+}
+
+// Add up the stats each node has for the keys in its own range, of one bucket or of all if empty.
+// Each node is asked for the range the walk gives it, so replicas are never counted.
+func (c *Client) bucketStats(ctx context.Context, bucket string) (map[string]BucketStats, error) {
+	totals := make(map[string]BucketStats)
+	err := c.walkRing(ctx, big.NewInt(-1), func(address Address, after, end *big.Int) (bool, error) {
+		stats := make(map[string]BucketStats)
+		if err := c.call(ctx, address, "NodeActor.BucketStats", BucketStatsRequest{after, end, bucket}, &stats); err != nil {
+			return false, fmt.Errorf("bucket stats of %s: %v", address, err)
+		}
+		for name, s := range stats {
+			total := totals[name]
+			total.Keys += s.Keys
+			total.Bytes += s.Bytes
+			totals[name] = total
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// The stats of the live keys this node stores in a range in each bucket, or in one bucket if named
+func (n *Node) bucketStats(request BucketStatsRequest) (map[string]BucketStats, error) {
+	items, err := n.Data.Range(request.Start, request.End)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	stats := make(map[string]BucketStats)
+	for key, siblings := range items {
+		name, _, ok := splitBucket(key)
+		if !ok || (request.Bucket != "" && name != request.Bucket) {
+			continue
+		}
+		live := siblings.live(now)
+		if len(live) == 0 {
+			continue
+		}
+		s := stats[name]
+		s.Keys++
+		for _, item := range live {
+			s.Bytes += len(item.Value)
+		}
+		stats[name] = s
+	}
+	return stats, nil
+}
@@ -0,0 +1,124 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// A bucket of the client, failing the test if the name is refused
+func testBucket(t *testing.T, client *Client, name string) *Bucket {
+	t.Helper()
+	bucket, err := client.Bucket(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bucket
+}
+
+func TestBucketsKeepKeysApart(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	users := testBucket(t, client, "users")
+	// A plain key holding a NUL byte is not the key of a bucket
+	plain := Key("users" + bucketSeparator + "ann")
+	if err := client.Put(ctx, plain, "plain"); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Put(ctx, "ann", "in bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if err := testBucket(t, client, "users\x01").Put(ctx, "ann", "other bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := client.Get(ctx, plain); err != nil || value != "plain" {
+		t.Errorf("plain key: got %q, %v", value, err)
+	}
+	if value, err := users.Get(ctx, "ann"); err != nil || value != "in bucket" {
+		t.Errorf("bucket key: got %q, %v", value, err)
+	}
+	if value, err := client.Get(ctx, users.Key("ann")); err != nil || value != "in bucket" {
+		t.Errorf("bucket key through the client: got %q, %v", value, err)
+	}
+
+	if _, err := client.Bucket("a" + bucketSeparator + "b"); err == nil {
+		t.Error("bucket name with a NUL byte accepted")
+	}
+	if err := client.Put(ctx, bucketPrefix+"no separator", "v"); err != ErrReservedKey {
+		t.Errorf("put of a malformed bucket key: %v", err)
+	}
+	if shown := users.Key("ann").String(); !strings.HasSuffix(shown, "[ users/ann ]") {
+		t.Errorf("bucket key shown as %q", shown)
+	}
+}
+
+func TestBucketKeysAndDrop(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	users := testBucket(t, client, "users")
+	for i := 0; i < 20; i++ {
+		if err := users.Put(ctx, fmt.Sprint("user", i), fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.Put(ctx, "plain", "v"); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := users.Keys(ctx, "", "user1*", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Keys) != 11 || page.Cursor != "" {
+		t.Errorf("listed %q matching user1*, expected 11 keys", page.Keys)
+	}
+	if keys := scanAll(t, client, "", 100); len(keys) != 1 || keys[0] != "plain" {
+		t.Errorf("scan listed %q, expected only the plain key", keys)
+	}
+
+	if deleted, err := users.Drop(ctx); err != nil || deleted != 20 {
+		t.Errorf("dropped %d keys, %v", deleted, err)
+	}
+	if page, err := users.Keys(ctx, "", "", 0); err != nil || len(page.Keys) != 0 {
+		t.Errorf("after the drop listed %q, %v", page.Keys, err)
+	}
+	if value, err := client.Get(ctx, "plain"); err != nil || value != "v" {
+		t.Errorf("plain key after the drop: got %q, %v", value, err)
+	}
+}
+
+func TestBucketStatsSkipReplicas(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	users := testBucket(t, client, "users")
+	for i := 0; i < 20; i++ {
+		if err := users.Put(ctx, fmt.Sprint("user", i), "ab"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := testBucket(t, client, "orders").Put(ctx, "order", "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A node that has lost its predecessor still only counts its own range
+	nodes[1].actor.run(func(n *Node) {
+		n.Predecessor = ""
+	})
+	buckets, err := client.Buckets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 2 || buckets["users"] != (BucketStats{20, 40}) || buckets["orders"] != (BucketStats{1, 3}) {
+		t.Errorf("got stats %+v", buckets)
+	}
+	if stats, err := users.Stats(ctx); err != nil || stats != (BucketStats{20, 40}) {
+		t.Errorf("users: got %+v, %v", stats, err)
+	}
+}
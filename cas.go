@@ -0,0 +1,23 @@
+package chord
+
+// Condition is what must be stored under a key for a conditional write to go ahead.
+// Every field that is set must hold. The zero Condition always holds.
+type Condition struct {
+	Absent  bool     // The key must not be stored
+	Version *Version // The key must have exactly one version, this one
+	Value   *string  // The key must have exactly one version, with this value
+}
+
+// Check a condition against the stored versions of a key
+func (c Condition) check(siblings Siblings) error {
+	if c.Absent && len(siblings) > 0 {
+		return ErrConditionFailed
+	}
+	if c.Version != nil && (len(siblings) != 1 || !siblings[0].Version.same(*c.Version)) {
+		return ErrConditionFailed
+	}
+	if c.Value != nil && (len(siblings) != 1 || string(siblings[0].Value) != *c.Value) {
+		return ErrConditionFailed
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package chord
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestConditionCheck(t *testing.T) {
+	item := Item{Value: []byte("v"), Version: Version{"n1", 1, VectorClock{}}}
+	other := Item{Value: []byte("w"), Version: Version{"n2", 1, VectorClock{}}}
+	value, wrong := "v", "w"
+	tests := []struct {
+		name      string
+		condition Condition
+		siblings  Siblings
+		holds     bool
+	}{
+		{"no condition", Condition{}, Siblings{item}, true},
+		{"absent on a missing key", Condition{Absent: true}, nil, true},
+		{"absent on a stored key", Condition{Absent: true}, Siblings{item}, false},
+		{"matching version", Condition{Version: &item.Version}, Siblings{item}, true},
+		{"other version", Condition{Version: &other.Version}, Siblings{item}, false},
+		{"version with siblings", Condition{Version: &item.Version}, Siblings{item, other}, false},
+		{"version of a missing key", Condition{Version: &item.Version}, nil, false},
+		{"matching value", Condition{Value: &value}, Siblings{item}, true},
+		{"other value", Condition{Value: &wrong}, Siblings{item}, false},
+	}
+	for _, test := range tests {
+		if err := test.condition.check(test.siblings); (err == nil) != test.holds {
+			t.Errorf("%s: got %v", test.name, err)
+		}
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.PutIfAbsent(ctx, "counter", "0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PutIfAbsent(ctx, "counter", "0"); err != ErrConditionFailed {
+		t.Fatalf("second put if absent: %v", err)
+	}
+
+	// Concurrent increments retry until their swap goes through, so none are lost
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				siblings, err := client.GetVersions(ctx, "counter", Consistency{})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				count, _ := strconv.Atoi(string(siblings[0].Value))
+				err = client.CompareAndSwap(ctx, "counter", siblings[0].Version, strconv.Itoa(count+1))
+				if err != ErrConditionFailed {
+					if err != nil {
+						t.Error(err)
+					}
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if value, err := client.Get(ctx, "counter"); err != nil || value != "10" {
+		t.Fatalf("after 10 increments: got %q, %v", value, err)
+	}
+
+	stale := "9"
+	if _, err := client.DeleteIf(ctx, "counter", Condition{Value: &stale}, Consistency{}); err != ErrConditionFailed {
+		t.Errorf("delete with a stale value: %v", err)
+	}
+	siblings, err := client.GetVersions(ctx, "counter", Consistency{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := client.DeleteIf(ctx, "counter", Condition{Version: &siblings[0].Version}, Consistency{}); err != nil || value != "10" {
+		t.Errorf("delete with the current version: got %q, %v", value, err)
+	}
+}
@@ -0,0 +1,317 @@
+// Package chord implements a node in a Chord distributed hash table.
+//
+// A ring is started with Create and other nodes are added with Join. Any
+// member node can then store and retrieve items for the whole ring with
+// Put, Get and Delete, and hand its data off gracefully with Leave. A node
+// with a data directory can be brought back after a crash with Restart.
+// Processes that only need to use the ring can do the same through a
+// Client without becoming members.
+package chord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Create starts a node and creates a new chord ring with it as the only member
+func Create(cfg Config) (*Node, error) {
+	n, err := createNode(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.create(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Join starts a node and adds it to the existing chord ring that joinAddress is a member of
+func Join(cfg Config, joinAddress Address) (*Node, error) {
+	n, err := createNode(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.join(joinAddress); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Start serving as the only member of a new ring. The node is stopped if anything fails.
+func (n *Node) create() error {
+	if err := n.startNode(); err != nil {
+		n.stopNode()
+		return fmt.Errorf("starting node RPC server: %v", err)
+	}
+	n.logger.Println("created ring successfully")
+	// Set successor to itself
+	n.actor.run(func(n *Node) {
+		n.Successors = append(n.Successors, n.Address)
+	})
+	// Start background tasks
+	if err := n.startBackgroundMaintenance(); err != nil {
+		n.stopNode()
+		return err
+	}
+	if n.config.HTTPPort != 0 {
+		if err := n.startGateway(); err != nil {
+			n.stopNode()
+			return err
+		}
+	}
+	return nil
+}
+
+// Find the node's place on the ring that joinAddress is a member of and start serving there,
+// taking over the items that now belong to it. The node is stopped if anything fails.
+func (n *Node) join(joinAddress Address) error {
+	// Call find starting at supplied address, searching for local address
+	ctx, cancel := context.WithTimeout(n.ctx, n.config.LookupTimeout)
+	defer cancel()
+	successor, err := n.client.find(ctx, n.Address.hashed(), joinAddress)
+	if err != nil {
+		n.stopNode()
+		return fmt.Errorf("finding place on ring: %v", err)
+	}
+	if successor == n.Address {
+		// A node restarted at its old address before the ring noticed it had gone
+		n.stopNode()
+		return errors.New("finding place on ring: the ring still lists this address, try again once it notices the node left")
+	}
+	// Now start server
+	if err := n.startNode(); err != nil {
+		n.stopNode()
+		return fmt.Errorf("starting node RPC server: %v", err)
+	}
+	n.logger.Printf("joining ring @ %s\n", successor)
+	// Set successor
+	n.actor.run(func(n *Node) {
+		n.Successors = append(n.Successors, successor)
+	})
+	// Start background tasks
+	if err := n.startBackgroundMaintenance(); err != nil {
+		n.stopNode()
+		return err
+	}
+	// Ask for successor for any data that should be ours
+	data := make(map[Key]Siblings)
+	if err := n.client.call(n.ctx, successor, "NodeActor.GetAll", n.Address, &data); err != nil {
+		n.stopNode()
+		return fmt.Errorf("transferring data from successor: %v", err)
+	}
+	n.actor.run(func(n *Node) {
+		for key, siblings := range data {
+			if err = n.merge(key, siblings...); err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		n.stopNode()
+		return fmt.Errorf("storing data from successor: %v", err)
+	}
+	n.logger.Println("Successfully transferred data to successor")
+	if n.config.HTTPPort != 0 {
+		if err := n.startGateway(); err != nil {
+			n.stopNode()
+			return err
+		}
+	}
+	return nil
+}
+
+// Leave offloads all local data and hints to the successor and shuts the node down.
+// If the node is the last one in the ring its data is lost.
+func (n *Node) Leave(ctx context.Context) error {
+	var successor Address
+	n.actor.run(func(n *Node) {
+		successor = n.Successors[0]
+	})
+	if successor != n.Address {
+		var data map[Key]Siblings
+		var err error
+		n.actor.run(func(n *Node) {
+			data, err = n.Data.Snapshot()
+		})
+		if err != nil {
+			return fmt.Errorf("reading data to offload: %v", err)
+		}
+		if err := n.client.call(ctx, successor, "NodeActor.PutAll", data, &None{}); err != nil {
+			return fmt.Errorf("offloading data to successor: %v", err)
+		}
+		n.logger.Println("Successfully offloaded data to successor")
+		var hints []Hint
+		n.actor.run(func(n *Node) {
+			hints = n.hints
+		})
+		for _, hint := range hints {
+			if err := n.client.call(ctx, successor, "NodeActor.Hint", hint, &None{}); err != nil {
+				return fmt.Errorf("offloading hints to successor: %v", err)
+			}
+		}
+	}
+	if err := n.stopNode(); err != nil {
+		return fmt.Errorf("stopping node: %v", err)
+	}
+	return nil
+}
+
+// Lookup returns the address of the node responsible for a key
+func (n *Node) Lookup(ctx context.Context, key Key) (Address, error) {
+	return n.client.Lookup(ctx, key)
+}
+
+// Put stores a key/value pair on the node responsible for the key using the ring's default consistency
+func (n *Node) Put(ctx context.Context, key Key, value string) error {
+	return n.PutWith(ctx, key, value, Consistency{})
+}
+
+// PutWith stores a key/value pair, succeeding once consistency.W replicas have acknowledged it
+func (n *Node) PutWith(ctx context.Context, key Key, value string, consistency Consistency) error {
+	return n.PutVersion(ctx, key, value, nil, consistency)
+}
+
+// PutBytes stores a binary value under a key, succeeding once consistency.W replicas have acknowledged it
+func (n *Node) PutBytes(ctx context.Context, key Key, value []byte, consistency Consistency) error {
+	return n.client.PutBytes(ctx, key, value, consistency)
+}
+
+// PutLarge stores a value of any size under a key, splitting it into chunks stored across the ring if needed
+func (n *Node) PutLarge(ctx context.Context, key Key, value []byte, consistency Consistency) error {
+	return n.client.PutLarge(ctx, key, value, consistency)
+}
+
+// PutTTL stores a key/value pair that disappears after ttl, replacing every version of the key
+func (n *Node) PutTTL(ctx context.Context, key Key, value string, ttl time.Duration, consistency Consistency) error {
+	if err := n.client.PutTTL(ctx, key, value, ttl, consistency); err != nil {
+		return err
+	}
+	n.logger.Println("successful put: ", KeyValue{key, []byte(value)})
+	return nil
+}
+
+// PutVersion stores a new version of a key that replaces the versions in seen, keeping any others as siblings
+func (n *Node) PutVersion(ctx context.Context, key Key, value string, seen VectorClock, consistency Consistency) error {
+	if err := n.client.PutVersion(ctx, key, value, seen, consistency); err != nil {
+		return err
+	}
+	n.logger.Println("successful put: ", KeyValue{key, []byte(value)})
+	return nil
+}
+
+// PutIf stores a value that replaces every version of a key, but only if the key is stored as the condition expects
+func (n *Node) PutIf(ctx context.Context, key Key, value string, condition Condition, consistency Consistency) error {
+	return n.client.PutIf(ctx, key, value, condition, consistency)
+}
+
+// PutIfAbsent stores a key/value pair only if the key is not stored yet
+func (n *Node) PutIfAbsent(ctx context.Context, key Key, value string) error {
+	return n.client.PutIfAbsent(ctx, key, value)
+}
+
+// CompareAndSwap replaces the value of a key only if its one current version is the expected one
+func (n *Node) CompareAndSwap(ctx context.Context, key Key, expected Version, value string) error {
+	return n.client.CompareAndSwap(ctx, key, expected, value)
+}
+
+// Get retrieves the value of a key from the node responsible for it using the ring's default consistency
+func (n *Node) Get(ctx context.Context, key Key) (string, error) {
+	return n.client.Get(ctx, key)
+}
+
+// GetWith retrieves the value of a key once consistency.R replicas have replied
+func (n *Node) GetWith(ctx context.Context, key Key, consistency Consistency) (string, error) {
+	return n.client.GetWith(ctx, key, consistency)
+}
+
+// GetBytes retrieves the binary value of a key once consistency.R replicas have replied
+func (n *Node) GetBytes(ctx context.Context, key Key, consistency Consistency) ([]byte, error) {
+	return n.client.GetBytes(ctx, key, consistency)
+}
+
+// GetLarge retrieves a value stored with PutLarge, fetching its chunks if it was split
+func (n *Node) GetLarge(ctx context.Context, key Key, consistency Consistency) ([]byte, error) {
+	return n.client.GetLarge(ctx, key, consistency)
+}
+
+// GetVersions retrieves every concurrent version of a key once consistency.R replicas have replied
+func (n *Node) GetVersions(ctx context.Context, key Key, consistency Consistency) (Siblings, error) {
+	return n.client.GetVersions(ctx, key, consistency)
+}
+
+// Delete removes a key from the node responsible for it using the ring's default consistency and returns the deleted value
+func (n *Node) Delete(ctx context.Context, key Key) (string, error) {
+	return n.client.Delete(ctx, key)
+}
+
+// DeleteWith removes a key, succeeding once consistency.W replicas have removed it
+func (n *Node) DeleteWith(ctx context.Context, key Key, consistency Consistency) (string, error) {
+	return n.client.DeleteWith(ctx, key, consistency)
+}
+
+// DeleteIf removes every version of a key only if the key is stored as the condition expects
+func (n *Node) DeleteIf(ctx context.Context, key Key, condition Condition, consistency Consistency) (string, error) {
+	return n.client.DeleteIf(ctx, key, condition, consistency)
+}
+
+// MultiPut stores many key/value pairs with one call to each node responsible for some of them
+func (n *Node) MultiPut(ctx context.Context, items map[Key]string, consistency Consistency) error {
+	return n.client.MultiPut(ctx, items, consistency)
+}
+
+// MultiPutBytes stores many binary values with one call to each node responsible for some of them
+func (n *Node) MultiPutBytes(ctx context.Context, items map[Key][]byte, consistency Consistency) error {
+	return n.client.MultiPutBytes(ctx, items, consistency)
+}
+
+// MultiGet retrieves the versions of many keys with one call to each node responsible for some of them
+func (n *Node) MultiGet(ctx context.Context, keys []Key, consistency Consistency) (map[Key]Siblings, error) {
+	return n.client.MultiGet(ctx, keys, consistency)
+}
+
+// MultiDelete removes many keys with one call to each node responsible for some of them and returns the deleted values
+func (n *Node) MultiDelete(ctx context.Context, keys []Key, consistency Consistency) (map[Key]string, error) {
+	return n.client.MultiDelete(ctx, keys, consistency)
+}
+
+// Scan lists up to limit keys in the order of their hashes, starting after the cursor of an earlier page
+func (n *Node) Scan(ctx context.Context, cursor string, pattern string, limit int) (ScanPage, error) {
+	return n.client.Scan(ctx, cursor, pattern, limit)
+}
+
+// Watch calls handle for every change to a key until the context is done, starting with its current versions if it is stored
+func (n *Node) Watch(ctx context.Context, key Key, handle func(WatchEvent)) error {
+	return n.client.Watch(ctx, key, handle)
+}
+
+// Bucket returns the namespace of keys with a name
+func (n *Node) Bucket(name string) (*Bucket, error) {
+	return n.client.Bucket(name)
+}
+
+// Buckets returns the stats of every bucket holding keys, by name
+func (n *Node) Buckets(ctx context.Context) (map[string]BucketStats, error) {
+	return n.client.Buckets(ctx)
+}
+
+// Dump retrieves the dump info of the node at an address
+func (n *Node) Dump(ctx context.Context, address Address) (DumpReturn, error) {
+	return n.client.Dump(ctx, address)
+}
+
+// Ping tests the RPC connection to a node.
+// A new connection is made for each call, use a Client to reuse connections.
+func Ping(ctx context.Context, address Address) error {
+	var success bool
+	return call(ctx, address, "NodeActor.Ping", None{}, &success)
+}
+
+// Dump retrieves the dump info of the node at an address.
+// A new connection is made for each call, use a Client to reuse connections.
+func Dump(ctx context.Context, address Address) (DumpReturn, error) {
+	var dump DumpReturn
+	err := call(ctx, address, "NodeActor.Dump", None{}, &dump)
+	return dump, err
+}
@@ -0,0 +1,279 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Settings for a node on a simulated network. The ring is maintained quickly so it forms in milliseconds,
+// while anti-entropy, hint delivery and expiry only run when a test calls them.
+func testConfig(network *MemoryNetwork, address Address) Config {
+	cfg := DefaultConfig()
+	cfg.StabilizeInterval = 10 * time.Millisecond
+	cfg.FixFingersInterval = 5 * time.Millisecond
+	cfg.CheckPredecessorInterval = 10 * time.Millisecond
+	cfg.AntiEntropyInterval = time.Hour
+	cfg.HintInterval = time.Hour
+	cfg.ExpireInterval = time.Hour
+	cfg.CallTimeout = 200 * time.Millisecond
+	cfg.LookupTimeout = 2 * time.Second
+	cfg.Logger = log.New(ioutil.Discard, "", 0)
+	cfg.Transport = network.Transport(address)
+	return cfg
+}
+
+// Start a ring of count nodes on a simulated network and wait for it to form.
+// The config of each node can be changed before it starts. The nodes are stopped when the test ends.
+func testRing(t *testing.T, network *MemoryNetwork, count int, configure func(*Config)) []*Node {
+	t.Helper()
+	nodes := []*Node{}
+	t.Cleanup(func() {
+		for _, n := range nodes {
+			n.stopNode()
+		}
+	})
+	for i := 0; i < count; i++ {
+		cfg := testConfig(network, Address(fmt.Sprintf("node%d", i)))
+		if configure != nil {
+			configure(&cfg)
+		}
+		var n *Node
+		var err error
+		if i == 0 {
+			n, err = Create(cfg)
+		} else {
+			n, err = Join(cfg, nodes[0].Address)
+		}
+		if err != nil {
+			t.Fatalf("starting node %d: %v", i, err)
+		}
+		nodes = append(nodes, n)
+	}
+	waitForRing(t, nodes)
+	return nodes
+}
+
+// Fail the test if the nodes do not form a ring in time, with successor lists long enough to hold their replicas
+func waitForRing(t *testing.T, nodes []*Node) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := WaitForRing(ctx, nodes); err != nil {
+		t.Fatal(err)
+	}
+	// Successor lists fill in a few rounds after the ring forms, and until then a node has fewer replicas
+	for !replicasFound(nodes) {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("successor lists did not fill in")
+		}
+	}
+}
+
+// Whether every node has found as many replicas as it keeps, or every other node if there are fewer
+func replicasFound(nodes []*Node) bool {
+	for _, n := range nodes {
+		var targets []Address
+		n.actor.run(func(n *Node) {
+			targets = n.replicaTargets(n.config.Replicas - 1)
+		})
+		if len(targets) < n.config.Replicas-1 && len(targets) < len(nodes)-1 {
+			return false
+		}
+	}
+	return true
+}
+
+// A client of the ring seeded with every node, closed when the test ends
+func testClient(t *testing.T, network *MemoryNetwork, nodes []*Node) *Client {
+	t.Helper()
+	seeds := []Address{}
+	for _, n := range nodes {
+		seeds = append(seeds, n.Address)
+	}
+	client, err := NewClient(testConfig(network, "client"), seeds...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// The versions a node stores for a key, read through its actor
+func stored(n *Node, key Key) Siblings {
+	var siblings Siblings
+	n.actor.run(func(n *Node) {
+		siblings, _ = n.Data.Get(key)
+	})
+	return siblings
+}
+
+// The nodes in the order of their positions on the ring
+func byHash(nodes []*Node) []*Node {
+	ordered := append([]*Node{}, nodes...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Hash.Cmp(ordered[j].Hash) < 0
+	})
+	return ordered
+}
+
+// The node that should own a key, and the nodes that follow it round the ring
+func ownerOf(nodes []*Node, key Key) []*Node {
+	ordered := byHash(nodes)
+	for i, n := range ordered {
+		if key.hashed().Cmp(n.Hash) <= 0 {
+			return append(ordered[i:], ordered[:i]...)
+		}
+	}
+	return ordered
+}
+
+func TestLeaveHandsOffData(t *testing.T) {
+	network := NewMemoryNetwork()
+	// With one copy of each item only the leaving node's hand-off keeps its keys
+	nodes := testRing(t, network, 3, func(cfg *Config) { cfg.Replicas = 1 })
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		if err := client.Put(ctx, Key(fmt.Sprint("key", i)), fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ordered := byHash(nodes)
+	leaving, successor := ordered[1], ordered[2]
+	hint := Hint{Owner: "elsewhere", Request: PutRequest{KeyValue: KeyValue{Key: "hinted", Value: []byte("v")}}, Received: time.Now()}
+	var err error
+	leaving.actor.run(func(n *Node) {
+		err = n.holdHint(hint)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := leaving.Leave(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var hints []Hint
+	successor.actor.run(func(n *Node) {
+		hints = append(hints, n.hints...)
+	})
+	if len(hints) != 1 || hints[0].Request.Key != "hinted" {
+		t.Errorf("successor holds hints %v after the leave", hints)
+	}
+	waitForRing(t, []*Node{ordered[0], successor})
+	for i := 0; i < 20; i++ {
+		key := Key(fmt.Sprint("key", i))
+		if value, err := client.Get(ctx, key); err != nil || value != fmt.Sprint(i) {
+			t.Errorf("%s after the leave: got %q, %v", string(key), value, err)
+		}
+	}
+}
+
+func TestLeaveLastNode(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 1, nil)
+	if err := nodes[0].Put(context.Background(), "key", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if err := nodes[0].Leave(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var success bool
+	if err := network.Transport("client").Call(ctx, nodes[0].Address, "NodeActor.Ping", None{}, &success); err == nil {
+		t.Error("node still answers after leaving")
+	}
+}
+
+func TestNodeMethods(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	n := nodes[0]
+	ctx := context.Background()
+	check := func(what string, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+	}
+
+	check("put", n.Put(ctx, "key", "v1"))
+	if value, err := n.Get(ctx, "key"); err != nil || value != "v1" {
+		t.Errorf("get: got %q, %v", value, err)
+	}
+	check("put with", n.PutWith(ctx, "key", "v2", Consistency{W: 3}))
+	if value, err := n.GetWith(ctx, "key", Consistency{R: 3}); err != nil || value != "v2" {
+		t.Errorf("get with: got %q, %v", value, err)
+	}
+	check("put bytes", n.PutBytes(ctx, "bytes", []byte{0, 1, 2}, Consistency{}))
+	if value, err := n.GetBytes(ctx, "bytes", Consistency{}); err != nil || string(value) != "\x00\x01\x02" {
+		t.Errorf("get bytes: got %q, %v", value, err)
+	}
+	check("put large", n.PutLarge(ctx, "large", []byte("large value"), Consistency{}))
+	if value, err := n.GetLarge(ctx, "large", Consistency{}); err != nil || string(value) != "large value" {
+		t.Errorf("get large: got %q, %v", value, err)
+	}
+	check("put TTL", n.PutTTL(ctx, "ttl", "v", time.Hour, Consistency{}))
+	if siblings, err := n.GetVersions(ctx, "ttl", Consistency{}); err != nil || len(siblings) != 1 || siblings[0].Expires.IsZero() {
+		t.Errorf("get versions of a put with a TTL: got %v, %v", siblings, err)
+	}
+
+	siblings, err := n.GetVersions(ctx, "key", Consistency{})
+	check("get versions", err)
+	check("put version", n.PutVersion(ctx, "key", "v3", siblings.Context(), Consistency{}))
+	if err := n.PutIf(ctx, "key", "v4", Condition{Absent: true}, Consistency{}); err != ErrConditionFailed {
+		t.Errorf("put if absent over a stored key: %v", err)
+	}
+	if err := n.PutIfAbsent(ctx, "key", "v4"); err != ErrConditionFailed {
+		t.Errorf("put if absent: %v", err)
+	}
+	siblings, err = n.GetVersions(ctx, "key", Consistency{})
+	check("get versions", err)
+	check("compare and swap", n.CompareAndSwap(ctx, "key", siblings[0].Version, "v5"))
+	if _, err := n.DeleteIf(ctx, "key", Condition{Version: &siblings[0].Version}, Consistency{}); err != ErrConditionFailed {
+		t.Errorf("delete if with a replaced version: %v", err)
+	}
+	if deleted, err := n.DeleteWith(ctx, "key", Consistency{W: 3}); err != nil || deleted != "v5" {
+		t.Errorf("delete with: got %q, %v", deleted, err)
+	}
+	if _, err := n.Delete(ctx, "key"); err != ErrNoSuchKey {
+		t.Errorf("delete of a deleted key: %v", err)
+	}
+
+	check("multi put", n.MultiPut(ctx, map[Key]string{"multi1": "1", "multi2": "2"}, Consistency{}))
+	check("multi put bytes", n.MultiPutBytes(ctx, map[Key][]byte{"multi3": {3}}, Consistency{}))
+	if items, err := n.MultiGet(ctx, []Key{"multi1", "multi2", "multi3"}, Consistency{}); err != nil || len(items) != 3 {
+		t.Errorf("multi get: got %v, %v", items, err)
+	}
+	page, err := n.Scan(ctx, "", "multi*", 10)
+	check("scan", err)
+	if len(page.Keys) != 3 {
+		t.Errorf("scan listed %q", page.Keys)
+	}
+	if deleted, err := n.MultiDelete(ctx, []Key{"multi1", "multi2", "multi3"}, Consistency{}); err != nil || len(deleted) != 3 {
+		t.Errorf("multi delete: got %v, %v", deleted, err)
+	}
+
+	bucket, err := n.Bucket("users")
+	check("bucket", err)
+	check("bucket put", bucket.Put(ctx, "ann", "v"))
+	if buckets, err := n.Buckets(ctx); err != nil || buckets["users"].Keys != 1 {
+		t.Errorf("buckets: got %v, %v", buckets, err)
+	}
+
+	owner, err := n.Lookup(ctx, "key")
+	check("lookup", err)
+	if expected := ownerOf(nodes, "key")[0].Address; owner != expected {
+		t.Errorf("lookup found %s, expected %s", owner, expected)
+	}
+	if dump, err := n.Dump(ctx, owner); err != nil || dump.Dump == "" || dump.Successor == "" {
+		t.Errorf("dump: got %+v, %v", dump, err)
+	}
+}
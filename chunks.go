@@ -0,0 +1,205 @@
+package chord
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Values too large for a single key are split into chunks stored in the reserved namespace under the key and the SHA-1
+// of their content, so they spread across the ring and identical chunks of a value are stored once. A manifest listing
+// the chunks in order is stored under the original key once every chunk is in place, so readers never see a partial value.
+// The manifest is flagged as one on its item, so no ordinary value is mistaken for it.
+//
+// Chunks outlive their manifest when the key is overwritten, deleted, expires or has its bucket dropped. The owner of a
+// chunk collects it once no live version of its key lists it, on two sweeps at least Config.ChunkGrace apart, which leaves
+// a put that has stored its chunks time to store its manifest.
+
+const chunkPrefix = reservedPrefix + "chunk/" // Starts the key of every chunk, followed by its hash, a slash and the key of its value
+
+// Where the chunks of a large value are stored
+type manifest struct {
+	Size   int      `json:"size"`
+	Chunks []string `json:"chunks"` // The hex SHA-1 of each chunk in order
+}
+
+// The key a chunk of a key's value is stored under
+func chunkKey(key Key, hash string) Key {
+	return Key(chunkPrefix + hash + "/" + string(key))
+}
+
+// Split the key of a chunk into the key of its value and its hash, if it is the key of a chunk
+func splitChunk(chunk Key) (key Key, hash string, ok bool) {
+	if !strings.HasPrefix(string(chunk), chunkPrefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(string(chunk)[len(chunkPrefix):], "/", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return Key(parts[1]), parts[0], true
+}
+
+// PutLarge stores a value of any size under a key. Values larger than the client's chunk size are split into chunks
+// stored across the ring, with a manifest under the key, and smaller ones are stored as they are.
+// Chunks no longer listed by the key are collected by the nodes holding them.
+func (c *Client) PutLarge(ctx context.Context, key Key, value []byte, consistency Consistency) error {
+	return c.putLarge(ctx, PutRequest{KeyValue: KeyValue{key, value}, Consistency: consistency})
+}
+
+// Send a put of a value of any size, storing its chunks before its manifest if it is split.
+// The TTL of the put applies to the manifest, and the chunks are collected once it has expired.
+func (c *Client) putLarge(ctx context.Context, request PutRequest) error {
+	key, value := request.Key, request.Value
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if len(value) <= c.chunkSize {
+		return c.put(ctx, request)
+	}
+	m := manifest{Size: len(value)}
+	chunks := make(map[Key][]byte)
+	for start := 0; start < len(value); start += c.chunkSize {
+		end := start + c.chunkSize
+		if end > len(value) {
+			end = len(value)
+		}
+		hash := fmt.Sprintf("%x", sha1.Sum(value[start:end]))
+		m.Chunks = append(m.Chunks, hash)
+		chunks[chunkKey(key, hash)] = value[start:end]
+	}
+	if err := c.multiPut(ctx, chunks, request.Consistency); err != nil {
+		return fmt.Errorf("storing chunks: %v", err)
+	}
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	request.Value, request.Manifest = encoded, true
+	return c.put(ctx, request)
+}
+
+// GetLarge retrieves a value stored with PutLarge, fetching and checking its chunks if it was split.
+// ErrNoSuchKey is returned if the key is not stored, and a *ConflictError if it has concurrent versions.
+func (c *Client) GetLarge(ctx context.Context, key Key, consistency Consistency) ([]byte, error) {
+	siblings, err := c.GetVersions(ctx, key, consistency)
+	if err != nil {
+		return nil, err
+	}
+	if len(siblings) > 1 {
+		return nil, &ConflictError{key, siblings}
+	}
+	if !siblings[0].Manifest {
+		return siblings[0].Value, nil
+	}
+	var m manifest
+	if err := json.Unmarshal(siblings[0].Value, &m); err != nil {
+		return nil, fmt.Errorf("reading manifest of %s: %v", string(key), err)
+	}
+	keys := []Key{}
+	for _, hash := range m.Chunks {
+		keys = append(keys, chunkKey(key, hash))
+	}
+	chunks, err := c.multiGet(ctx, keys, consistency)
+	if err != nil {
+		return nil, fmt.Errorf("fetching chunks: %v", err)
+	}
+	assembled := make([]byte, 0, m.Size)
+	for _, hash := range m.Chunks {
+		chunk, found := []byte(nil), false
+		// Copies of a chunk written concurrently are siblings with the same content
+		for _, item := range chunks[chunkKey(key, hash)] {
+			if fmt.Sprintf("%x", sha1.Sum(item.Value)) == hash {
+				chunk, found = item.Value, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("chunk %s of %s is missing or corrupt", hash, string(key))
+		}
+		assembled = append(assembled, chunk...)
+	}
+	if len(assembled) != m.Size {
+		return nil, fmt.Errorf("chunks of %s hold %d bytes instead of %d", string(key), len(assembled), m.Size)
+	}
+	return assembled, nil
+}
+
+// The hashes of the chunks listed by the live manifests of a key
+func (c *Client) listedChunks(ctx context.Context, key Key) (map[string]bool, error) {
+	listed := make(map[string]bool)
+	siblings, err := c.GetVersions(ctx, key, Consistency{})
+	if err == ErrNoSuchKey {
+		return listed, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, item := range siblings {
+		if !item.Manifest {
+			continue
+		}
+		var m manifest
+		if err := json.Unmarshal(item.Value, &m); err != nil {
+			return nil, fmt.Errorf("reading manifest of %s: %v", string(key), err)
+		}
+		for _, hash := range m.Chunks {
+			listed[hash] = true
+		}
+	}
+	return listed, nil
+}
+
+// Delete the chunks this node owns that no live version of their key has listed for Config.ChunkGrace
+func (n *Node) collectChunks(ctx context.Context) error {
+	var owned map[Key]Siblings
+	var err error
+	n.actor.run(func(n *Node) {
+		owned, err = n.ownedData()
+	})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	byKey := make(map[Key][]Key)
+	for chunk, siblings := range owned {
+		if key, _, ok := splitChunk(chunk); ok && len(siblings.live(now)) > 0 {
+			byKey[key] = append(byKey[key], chunk)
+		}
+	}
+
+	orphans := make(map[Key]time.Time)
+	for key, chunks := range byKey {
+		listed, err := n.client.listedChunks(ctx, key)
+		if err != nil {
+			// Without the manifest there is no telling which chunks are still needed
+			n.logger.Printf("collect chunks: %v", err)
+			continue
+		}
+		for _, chunk := range chunks {
+			if _, hash, _ := splitChunk(chunk); !listed[hash] {
+				orphans[chunk] = now
+			}
+		}
+	}
+	collect := []Key{}
+	n.actor.run(func(n *Node) {
+		for chunk := range orphans {
+			if since, ok := n.orphans[chunk]; ok {
+				orphans[chunk] = since
+				if now.Sub(since) >= n.config.ChunkGrace {
+					collect = append(collect, chunk)
+				}
+			}
+		}
+		n.orphans = orphans
+	})
+	if len(collect) == 0 {
+		return nil
+	}
+	deleted, err := n.client.multiDelete(ctx, collect, Consistency{})
+	n.logger.Printf("collect chunks: deleted %d chunks no longer listed by their keys", len(deleted))
+	return err
+}
@@ -0,0 +1,160 @@
+package chord
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// A client of the ring that splits values into chunks of size bytes
+func chunkingClient(t *testing.T, network *MemoryNetwork, nodes []*Node, size int) *Client {
+	t.Helper()
+	cfg := testConfig(network, "client")
+	cfg.ChunkSize = size
+	client, err := NewClient(cfg, nodes[0].Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// The hashes of the live chunks of a key stored anywhere on the ring
+func liveChunks(nodes []*Node, key Key) map[string]bool {
+	chunks := make(map[string]bool)
+	now := time.Now()
+	for _, n := range nodes {
+		n.actor.run(func(n *Node) {
+			items, _ := n.Data.Snapshot()
+			for chunk, siblings := range items {
+				if of, hash, ok := splitChunk(chunk); ok && of == key && len(siblings.live(now)) > 0 {
+					chunks[hash] = true
+				}
+			}
+		})
+	}
+	return chunks
+}
+
+// A value of size bytes that differs for every seed
+func largeValue(seed string, size int) []byte {
+	var value bytes.Buffer
+	for i := 0; value.Len() < size; i++ {
+		fmt.Fprintf(&value, "%s-%d,", seed, i)
+	}
+	return value.Bytes()[:size]
+}
+
+func TestLargeValues(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := chunkingClient(t, network, nodes, 1000)
+	ctx := context.Background()
+	value := largeValue("a", 10500)
+	if err := client.PutLarge(ctx, "large", value, Consistency{}); err != nil {
+		t.Fatal(err)
+	}
+	if read, err := client.GetLarge(ctx, "large", Consistency{}); err != nil || !bytes.Equal(read, value) {
+		t.Fatalf("read %d bytes, %v", len(read), err)
+	}
+	if chunks := liveChunks(nodes, "large"); len(chunks) != 11 {
+		t.Errorf("stored %d chunks, expected 11", len(chunks))
+	}
+
+	// An ordinary value that looks like a manifest is read as it is
+	lookalike := []byte(`chord-manifest/1` + "\n" + `{"size":3,"chunks":["00"]}`)
+	if err := client.PutBytes(ctx, "small", lookalike, Consistency{}); err != nil {
+		t.Fatal(err)
+	}
+	if read, err := client.GetLarge(ctx, "small", Consistency{}); err != nil || !bytes.Equal(read, lookalike) {
+		t.Errorf("read %q, %v", read, err)
+	}
+
+	page, err := client.Scan(ctx, "", "", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Keys) != 2 {
+		t.Errorf("scan listed %q, expected only the two keys written", page.Keys)
+	}
+}
+
+func TestReservedKeys(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 1, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	reserved := chunkKey("key", "00")
+	if err := client.Put(ctx, reserved, "v"); err != ErrReservedKey {
+		t.Errorf("put: %v", err)
+	}
+	if _, err := client.Get(ctx, reserved); err != ErrReservedKey {
+		t.Errorf("get: %v", err)
+	}
+	if _, err := client.Delete(ctx, reserved); err != ErrReservedKey {
+		t.Errorf("delete: %v", err)
+	}
+	if err := client.MultiPut(ctx, map[Key]string{"plain": "v", reserved: "v"}, Consistency{}); err != ErrReservedKey {
+		t.Errorf("batched put: %v", err)
+	}
+	if _, err := client.MultiGet(ctx, []Key{reserved}, Consistency{}); err != ErrReservedKey {
+		t.Errorf("batched get: %v", err)
+	}
+}
+
+func TestChunksAreCollected(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, func(cfg *Config) {
+		cfg.ChunkGrace = 100 * time.Millisecond
+	})
+	client := chunkingClient(t, network, nodes, 1000)
+	ctx := context.Background()
+	collect := func() {
+		for _, n := range nodes {
+			if err := n.collectChunks(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := client.PutLarge(ctx, "large", largeValue("old", 5000), Consistency{}); err != nil {
+		t.Fatal(err)
+	}
+	old := liveChunks(nodes, "large")
+	value := largeValue("new", 5000)
+	if err := client.PutLarge(ctx, "large", value, Consistency{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Chunks are only collected once they have gone unlisted for the grace period
+	collect()
+	if chunks := liveChunks(nodes, "large"); len(chunks) != 10 {
+		t.Fatalf("%d chunks after the first sweep, expected 10", len(chunks))
+	}
+	time.Sleep(100 * time.Millisecond)
+	collect()
+	// The replicas of a chunk hear of its delete after the owner
+	eventually(t, "the chunks of the old value to be collected", func() bool {
+		return len(liveChunks(nodes, "large")) == 5
+	})
+	chunks := liveChunks(nodes, "large")
+	for hash := range old {
+		if chunks[hash] {
+			t.Errorf("chunk %s of the old value was kept", hash)
+		}
+	}
+	if read, err := client.GetLarge(ctx, "large", Consistency{}); err != nil || !bytes.Equal(read, value) {
+		t.Fatalf("after collecting: read %d bytes, %v", len(read), err)
+	}
+
+	if _, err := client.Delete(ctx, "large"); err != nil {
+		t.Fatal(err)
+	}
+	collect()
+	time.Sleep(100 * time.Millisecond)
+	collect()
+	eventually(t, "the chunks of a deleted key to be collected", func() bool {
+		return len(liveChunks(nodes, "large")) == 0
+	})
+}
@@ -0,0 +1,305 @@
+package chord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/rpc"
+	"strings"
+	"time"
+)
+
+// Client stores and retrieves items on a chord ring without being a member of it
+type Client struct {
+	seeds         []Address     // Known ring members used to start lookups
+	transport     Transport     // How calls reach ring members
+	ownTransport  bool          // Whether the client created the transport, so it is closed along with the client
+	callTimeout   time.Duration // The deadline for a single RPC
+	lookupTimeout time.Duration // The deadline for a whole lookup across the ring
+	chunkSize     int           // The size of the chunks large values are split into
+}
+
+// NewClient creates a client that reaches the ring through any of the seed addresses.
+// Only the transport, timeouts and chunk size are used from the config. A transport from the config
+// is left open when the client is closed, so it can be shared with other clients or with a node.
+func NewClient(cfg Config, seeds ...Address) (*Client, error) {
+	if len(seeds) == 0 {
+		return nil, errors.New("at least one seed address is required")
+	}
+	cfg = cfg.withDefaults()
+	if cfg.Transport != nil {
+		return newClient(cfg, seeds...), nil
+	}
+	cfg.Transport = NewRPCTransport(cfg)
+	client := newClient(cfg, seeds...)
+	client.ownTransport = true
+	return client, nil
+}
+
+func newClient(cfg Config, seeds ...Address) *Client {
+	return &Client{
+		seeds:         append([]Address{}, seeds...),
+		transport:     cfg.Transport,
+		callTimeout:   cfg.CallTimeout,
+		lookupTimeout: cfg.LookupTimeout,
+		chunkSize:     cfg.ChunkSize,
+	}
+}
+
+// Close releases all connections held by the client, unless its transport was given to it
+func (c *Client) Close() error {
+	if !c.ownTransport {
+		return nil
+	}
+	return c.transport.Close()
+}
+
+// Call a NodeActor method on a node with the per-call deadline
+func (c *Client) call(ctx context.Context, address Address, method string, request interface{}, reply interface{}) error {
+	return c.callFor(ctx, c.callTimeout, address, method, request, reply)
+}
+
+// Call a NodeActor method that the node coordinates with its replicas. The node's calls to the replicas have
+// the per-call deadline of their own, so the client waits for two before giving up on the node.
+func (c *Client) coordinate(ctx context.Context, address Address, method string, request interface{}, reply interface{}) error {
+	return c.callFor(ctx, 2*c.callTimeout, address, method, request, reply)
+}
+
+// Call a NodeActor method on a node with a deadline of its own, for calls that wait on purpose
+func (c *Client) callFor(ctx context.Context, timeout time.Duration, address Address, method string, request interface{}, reply interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := c.transport.Call(ctx, address, method, request, reply)
+	// Give back errors callers can compare against
+	if se, ok := err.(rpc.ServerError); ok {
+		for _, known := range []error{ErrNoSuchKey, ErrConditionFailed, ErrValueTooLarge} {
+			if string(se) == known.Error() {
+				return known
+			}
+		}
+	}
+	return err
+}
+
+// Lookup returns the address of the node responsible for a key.
+// Each seed is tried in turn until one of them completes the lookup.
+// The deadline covers all of the attempts.
+func (c *Client) Lookup(ctx context.Context, key Key) (Address, error) {
+	return c.lookupID(ctx, key.hashed())
+}
+
+// Find the node responsible for a position on the ring, trying each seed in turn
+func (c *Client) lookupID(ctx context.Context, id *big.Int) (Address, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.lookupTimeout)
+	defer cancel()
+
+	var err error
+	for _, seed := range c.seeds {
+		var address Address
+		if address, err = c.find(ctx, id, seed); err == nil || ctx.Err() != nil {
+			return address, err
+		}
+	}
+	return "", err
+}
+
+// Put stores a key/value pair on the node responsible for the key using the ring's default consistency.
+// The value replaces every version of the key.
+func (c *Client) Put(ctx context.Context, key Key, value string) error {
+	return c.PutWith(ctx, key, value, Consistency{})
+}
+
+// PutWith stores a key/value pair, succeeding once consistency.W replicas have acknowledged it.
+// The value replaces every version of the key.
+func (c *Client) PutWith(ctx context.Context, key Key, value string, consistency Consistency) error {
+	return c.PutVersion(ctx, key, value, nil, consistency)
+}
+
+// PutBytes stores a binary value under a key, succeeding once consistency.W replicas have acknowledged it.
+// The value replaces every version of the key. ErrValueTooLarge is returned if it is larger than the owner accepts, use PutLarge for those.
+func (c *Client) PutBytes(ctx context.Context, key Key, value []byte, consistency Consistency) error {
+	return c.put(ctx, PutRequest{KeyValue: KeyValue{key, value}, Consistency: consistency})
+}
+
+// PutTTL stores a key/value pair that disappears after ttl, replacing every version of the key.
+// The expiry is set by the owner when it accepts the put and copied with the item to every replica. A negative ttl is refused.
+func (c *Client) PutTTL(ctx context.Context, key Key, value string, ttl time.Duration, consistency Consistency) error {
+	return c.put(ctx, PutRequest{KeyValue: KeyValue{key, []byte(value)}, TTL: ttl, Consistency: consistency})
+}
+
+// PutVersion stores a new version of a key that replaces the versions in seen, usually the context of siblings read earlier.
+// Versions written since then are kept as siblings of the new value rather than being lost.
+// A nil seen clock replaces every version.
+func (c *Client) PutVersion(ctx context.Context, key Key, value string, seen VectorClock, consistency Consistency) error {
+	return c.put(ctx, PutRequest{KeyValue: KeyValue{key, []byte(value)}, Seen: seen, Consistency: consistency})
+}
+
+// PutIf stores a value that replaces every version of a key, but only if the key is stored as the condition expects.
+// ErrConditionFailed is returned if it is not.
+func (c *Client) PutIf(ctx context.Context, key Key, value string, condition Condition, consistency Consistency) error {
+	return c.put(ctx, PutRequest{KeyValue: KeyValue{key, []byte(value)}, Condition: condition, Consistency: consistency})
+}
+
+// PutIfAbsent stores a key/value pair only if the key is not stored yet.
+// ErrConditionFailed is returned if it is.
+func (c *Client) PutIfAbsent(ctx context.Context, key Key, value string) error {
+	return c.PutIf(ctx, key, value, Condition{Absent: true}, Consistency{})
+}
+
+// CompareAndSwap replaces the value of a key only if its one current version is the expected one.
+// ErrConditionFailed is returned if the key was written since that version was read.
+func (c *Client) CompareAndSwap(ctx context.Context, key Key, expected Version, value string) error {
+	return c.PutIf(ctx, key, value, Condition{Version: &expected}, Consistency{})
+}
+
+// Keys starting with a NUL byte are reserved for the items the ring keeps for its own use, such as the chunks of large values,
+// and for the keys of buckets. The client refuses every other reserved key, so they never collide with the keys of its users.
+const reservedPrefix = "\x00"
+
+// Whether a key is in the reserved namespace
+func (k Key) reserved() bool {
+	return strings.HasPrefix(string(k), reservedPrefix)
+}
+
+// Refuse a key a user cannot read or write
+func checkKey(key Key) error {
+	if _, _, inBucket := splitBucket(key); key.reserved() && !inBucket {
+		return ErrReservedKey
+	}
+	return nil
+}
+
+// Send a put to the node responsible for the key.
+// If that node cannot be reached the put is handed to one of the seeds as a hint, and succeeds once the seed holds it.
+func (c *Client) put(ctx context.Context, request PutRequest) error {
+	if err := checkKey(request.Key); err != nil {
+		return err
+	}
+	// Find address to put at
+	address, err := c.Lookup(ctx, request.Key)
+	if err != nil {
+		return fmt.Errorf("finding correct node to put at: %v", err)
+	}
+	// Now put it there
+	err = c.coordinate(ctx, address, "NodeActor.Put", request, &None{})
+	if c.shouldHint(ctx, address, request, err) {
+		// Leave the put with another node to deliver once the owner is back
+		if c.hint(ctx, address, request) == nil {
+			return nil
+		}
+	}
+	if err == ErrConditionFailed || err == ErrValueTooLarge {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("putting: %v", err)
+	}
+	return nil
+}
+
+// Get retrieves the value of a key from the node responsible for it using the ring's default consistency.
+// ErrNoSuchKey is returned if the key is not stored, and a *ConflictError if it has concurrent versions.
+func (c *Client) Get(ctx context.Context, key Key) (string, error) {
+	return c.GetWith(ctx, key, Consistency{})
+}
+
+// GetWith retrieves the value of a key once consistency.R replicas have replied.
+// ErrNoSuchKey is returned if none of them store the key, and a *ConflictError if it has concurrent versions.
+func (c *Client) GetWith(ctx context.Context, key Key, consistency Consistency) (string, error) {
+	value, err := c.GetBytes(ctx, key, consistency)
+	return string(value), err
+}
+
+// GetBytes retrieves the binary value of a key once consistency.R replicas have replied.
+// ErrNoSuchKey is returned if none of them store the key, and a *ConflictError if it has concurrent versions.
+// ErrLargeValue is returned if the value was split into chunks by PutLarge, use GetLarge for those.
+func (c *Client) GetBytes(ctx context.Context, key Key, consistency Consistency) ([]byte, error) {
+	siblings, err := c.GetVersions(ctx, key, consistency)
+	if err != nil {
+		return nil, err
+	}
+	if len(siblings) > 1 {
+		return nil, &ConflictError{key, siblings}
+	}
+	if siblings[0].Manifest {
+		return nil, ErrLargeValue
+	}
+	return siblings[0].Value, nil
+}
+
+// GetVersions retrieves every concurrent version of a key once consistency.R replicas have replied.
+// ErrNoSuchKey is returned if none of them store the key.
+func (c *Client) GetVersions(ctx context.Context, key Key, consistency Consistency) (Siblings, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	// Find address to get from
+	address, err := c.Lookup(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("finding correct node to get from: %v", err)
+	}
+	// Now get the versions
+	var siblings Siblings
+	if err := c.coordinate(ctx, address, "NodeActor.Get", GetRequest{key, consistency}, &siblings); err == ErrNoSuchKey {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("getting: %v", err)
+	}
+	return siblings, nil
+}
+
+// Delete removes every version of a key from the node responsible for it using the ring's default consistency and returns the deleted value.
+// ErrNoSuchKey is returned if the key is not stored.
+func (c *Client) Delete(ctx context.Context, key Key) (string, error) {
+	return c.DeleteWith(ctx, key, Consistency{})
+}
+
+// DeleteWith removes every version of a key, succeeding once consistency.W replicas have removed it.
+// The deleted value is returned, with all sibling values listed if there were conflicting versions.
+// Values split into chunks by PutLarge are left out, since only their manifest is at hand.
+// ErrNoSuchKey is returned if the key is not stored.
+func (c *Client) DeleteWith(ctx context.Context, key Key, consistency Consistency) (string, error) {
+	return c.delete(ctx, DeleteRequest{key, Condition{}, consistency})
+}
+
+// DeleteIf removes every version of a key only if the key is stored as the condition expects, and returns the deleted value.
+// ErrNoSuchKey is returned if the key is not stored and ErrConditionFailed if it is stored differently.
+func (c *Client) DeleteIf(ctx context.Context, key Key, condition Condition, consistency Consistency) (string, error) {
+	return c.delete(ctx, DeleteRequest{key, condition, consistency})
+}
+
+// Send a delete to the node responsible for the key
+func (c *Client) delete(ctx context.Context, request DeleteRequest) (string, error) {
+	if err := checkKey(request.Key); err != nil {
+		return "", err
+	}
+	// Find address to delete from
+	address, err := c.Lookup(ctx, request.Key)
+	if err != nil {
+		return "", fmt.Errorf("finding correct node to delete from: %v", err)
+	}
+	// Now delete the value
+	var siblings Siblings
+	if err := c.coordinate(ctx, address, "NodeActor.Delete", request, &siblings); err == ErrNoSuchKey || err == ErrConditionFailed {
+		return "", err
+	} else if err != nil {
+		return "", fmt.Errorf("deleting: %v", err)
+	}
+	values := Siblings{}
+	for _, item := range siblings {
+		if !item.Manifest {
+			values = append(values, item)
+		}
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+	return values.String(), nil
+}
+
+// Dump retrieves the dump info of the node at an address
+func (c *Client) Dump(ctx context.Context, address Address) (DumpReturn, error) {
+	var dump DumpReturn
+	err := c.call(ctx, address, "NodeActor.Dump", None{}, &dump)
+	return dump, err
+}
@@ -0,0 +1,1004 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evad1n/chord"
+)
+
+type (
+	command struct {
+		description     string
+		usage           string
+		do              func(string) error
+		joinRequired    bool // Whether the command requires a ring to function
+		connectRequired bool // Whether the command requires a ring to talk to, either as a member or a client
+	}
+)
+
+var (
+	commands   map[string]command // Map of command aliases to commands
+	ansiColors map[string]string  // ANSI colors to code map
+)
+
+// Displaying command widths
+var (
+	nameWidth        int
+	descriptionWidth int
+	usageWidth       int
+	totalWidth       int
+)
+
+// Initialize and populate lookup tables
+func createMaps() {
+
+	// ANSI colors map
+	ansiColors = make(map[string]string)
+	ansiColors["black"] = "\x1b[30m"
+	ansiColors["red"] = "\x1b[31m"
+	ansiColors["green"] = "\x1b[32m"
+	ansiColors["yellow"] = "\x1b[33m"
+	ansiColors["blue"] = "\x1b[34m"
+	ansiColors["magenta"] = "\x1b[35m"
+	ansiColors["cyan"] = "\x1b[36m"
+	ansiColors["white"] = "\x1b[37m"
+}
+
+/* Maps prefixes to full name for a map */
+func addMapPrefix(full string, m map[string]string) {
+	for i := range full {
+		if i == 0 {
+			continue
+		}
+		prefix := full[:i]
+		if _, exists := m[prefix]; !exists {
+			m[prefix] = full
+		}
+	}
+	m[full] = full
+}
+
+// Adds all starting commands
+func defaultCommands() {
+	commands = make(map[string]command)
+
+	commands["help"] = command{
+		description: "List all commands and descriptions",
+		do:          listCommands,
+	}
+	commands["quit"] = command{
+		description: "Quit and offload node data gracefully",
+		do:          quit,
+	}
+	commands["log"] = command{
+		description: "Turn logging ON/OFF",
+		usage:       "log <0|1>",
+		do:          setLogging,
+	}
+	commands["port"] = command{
+		description: "Change the listening port",
+		usage:       "port <number>",
+		do:          changePort,
+	}
+	commands["jsonport"] = command{
+		description: "Also serve JSON-RPC on a port, 0 disables it",
+		usage:       "jsonport <number>",
+		do:          changeJSONRPCPort,
+	}
+	commands["httpport"] = command{
+		description: "Also serve the HTTP gateway on a port, 0 disables it",
+		usage:       "httpport <number>",
+		do:          changeHTTPPort,
+	}
+	commands["timeout"] = command{
+		description: "Change the RPC call and whole lookup deadlines",
+		usage:       "timeout <call> <lookup>",
+		do:          setTimeouts,
+	}
+	commands["datadir"] = command{
+		description: "Keep this node's items and place on the ring in a directory so it can restart",
+		usage:       "datadir <directory> [sync=always|interval|never]",
+		do:          setDataDir,
+	}
+	commands["getaddr"] = command{
+		description: "Get the current node address",
+		do: func(_ string) error {
+			fmt.Println(chord.Address(config.AdvertiseAddress + ":" + fmt.Sprint(config.Port)))
+			return nil
+		},
+	}
+	commands["gethash"] = command{
+		description: "Print a hashed value",
+		usage:       "gethash <input>",
+		do: func(input string) error {
+			fmt.Println(chord.ReadableHash(chord.HashString(input)))
+			return nil
+		},
+	}
+	commands["ping"] = command{
+		description: "Ping another node",
+		usage:       "ping <host>:<port>",
+		do:          ping,
+	}
+	commands["create"] = command{
+		description: "Create and join a new chord ring",
+		do:          create,
+	}
+	commands["join"] = command{
+		description: "Join a chord ring from a known node address",
+		usage:       "join <host>:<port>",
+		do:          join,
+	}
+	commands["restart"] = command{
+		description: "Rejoin the ring after a crash with the address and items saved in the data directory",
+		do:          restart,
+	}
+	commands["connect"] = command{
+		description: "Use a chord ring as a client without joining it",
+		usage:       "connect <host>:<port> [<host>:<port>...]",
+		do:          connect,
+	}
+	commands["put"] = command{
+		description:     "Add a key/value pair to the database",
+		usage:           "put <key> <value> [ttl=<duration>] [n=<replicas>] [w=<acks>]",
+		do:              put,
+		connectRequired: true,
+	}
+	commands["get"] = command{
+		description:     "Get the value of a key and its version",
+		usage:           "get <key> [n=<replicas>] [r=<replies>]",
+		do:              get,
+		connectRequired: true,
+	}
+	commands["delete"] = command{
+		description:     "Delete a key and its associated value",
+		usage:           "delete <key> [n=<replicas>] [w=<acks>]",
+		do:              deleteKey,
+		connectRequired: true,
+	}
+	commands["cas"] = command{
+		description:     "Replace the value of a key only if it currently has the expected value",
+		usage:           "cas <key> <expected> <value> [n=<replicas>] [w=<acks>]",
+		do:              compareAndSwap,
+		connectRequired: true,
+	}
+	commands["putnx"] = command{
+		description:     "Insert a key/value pair only if the key is not stored yet",
+		usage:           "putnx <key> <value> [n=<replicas>] [w=<acks>]",
+		do:              putIfAbsent,
+		connectRequired: true,
+	}
+	commands["mput"] = command{
+		description:     "Add many key/value pairs, sending each node its pairs in one call",
+		usage:           "mput <key> <value> [<key> <value>...]",
+		do:              multiPut,
+		connectRequired: true,
+	}
+	commands["mget"] = command{
+		description:     "Get the values of many keys, asking each node for its keys in one call",
+		usage:           "mget <key> [<key>...]",
+		do:              multiGet,
+		connectRequired: true,
+	}
+	commands["putfile"] = command{
+		description:     "Store the contents of a file under a key, in chunks if it is large",
+		usage:           "putfile <key> <path>",
+		do:              putFile,
+		connectRequired: true,
+	}
+	commands["getfile"] = command{
+		description:     "Write the value of a key stored with putfile to a file",
+		usage:           "getfile <key> <path>",
+		do:              getFile,
+		connectRequired: true,
+	}
+	commands["watch"] = command{
+		description:     "Print every change to a key as it happens, until unwatched",
+		usage:           "watch <key>",
+		do:              watchKey,
+		connectRequired: true,
+	}
+	commands["unwatch"] = command{
+		description: "Stop printing the changes to a watched key",
+		usage:       "unwatch <key>",
+		do:          unwatchKey,
+	}
+	commands["keys"] = command{
+		description:     "List the keys in the ring in hash order, optionally only those matching a glob",
+		usage:           "keys [pattern]",
+		do:              listKeys,
+		connectRequired: true,
+	}
+	commands["bucket"] = command{
+		description:     "Use the keys of a bucket in the key commands, or plain keys with no name",
+		usage:           "bucket [name]",
+		do:              useBucket,
+		connectRequired: true,
+	}
+	commands["buckets"] = command{
+		description:     "List every bucket holding keys with its key count and size",
+		do:              listBuckets,
+		connectRequired: true,
+	}
+	commands["dropbucket"] = command{
+		description:     "Delete every key of a bucket",
+		usage:           "dropbucket <name>",
+		do:              dropBucket,
+		connectRequired: true,
+	}
+	commands["putrandom"] = command{
+		description:     "Add random data items to the database",
+		usage:           "putrandom <num_items>",
+		do:              putRandom,
+		connectRequired: true,
+	}
+	// Information/debugging
+	commands["dump"] = command{
+		description:  "Dumps current node information",
+		do:           dumpCurrent,
+		joinRequired: true,
+	}
+	commands["dumpkey"] = command{
+		description:     "Dumps info on the node responsible for a key",
+		usage:           "dumpkey <key>",
+		do:              dumpKey,
+		connectRequired: true,
+	}
+	commands["dumpaddr"] = command{
+		description: "Dumps info on the node at the requested address",
+		usage:       "dumpaddr <host>:<port>",
+		do:          dumpAddress,
+	}
+	commands["dumpall"] = command{
+		description:  "Dumps info on each node in the current ring",
+		do:           dumpAll,
+		joinRequired: true,
+	}
+
+	// Set display variables
+	for name, cmd := range commands {
+		if len(cmd.description) > descriptionWidth {
+			descriptionWidth = len(cmd.description)
+		}
+		if len(name) > nameWidth {
+			nameWidth = len(name)
+		}
+		if len(cmd.usage) > usageWidth {
+			usageWidth = len(cmd.usage)
+		}
+	}
+	totalWidth = nameWidth + descriptionWidth + usageWidth + 8
+}
+
+//////////////
+// Commands //
+//////////////
+
+// Lists known aliases for commands
+func listCommands(_ string) error {
+	var w strings.Builder
+
+	w.WriteString(fmt.Sprintf("+%s+\n", strings.Repeat("-", totalWidth)))
+	w.WriteString(fmt.Sprintf("|%s|\n", centerText("COMMANDS LIST", totalWidth, ' ')))
+	w.WriteString(fmt.Sprintf(
+		"+%s+%s+%s+\n",
+		strings.Repeat("-", nameWidth+2),
+		strings.Repeat("-", descriptionWidth+2),
+		strings.Repeat("-", usageWidth+2),
+	))
+	w.WriteString(fmt.Sprintf(
+		"| %s | %s | %s |\n",
+		centerText("NAME", nameWidth, ' '),
+		centerText("DESCRIPTION", descriptionWidth, ' '),
+		centerText("USAGE", usageWidth, ' '),
+	))
+	w.WriteString(fmt.Sprintf(
+		"+%s+%s+%s+\n",
+		strings.Repeat("-", nameWidth+2),
+		strings.Repeat("-", descriptionWidth+2),
+		strings.Repeat("-", usageWidth+2),
+	))
+
+	type namedCmd struct {
+		name string
+		command
+	}
+	cmds := []namedCmd{}
+	for name, cmd := range commands {
+		cmds = append(cmds, namedCmd{
+			name,
+			cmd,
+		})
+	}
+
+	// Sort by name
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].name < cmds[j].name
+	})
+
+	for _, c := range cmds {
+		w.WriteString(fmt.Sprintf("| %s | %s |", padText(c.name, nameWidth), padText(c.description, descriptionWidth)))
+		if c.usage != "" {
+			w.WriteString(fmt.Sprintf(" %-s |\n", padText(c.usage, usageWidth)))
+		} else {
+			w.WriteString(fmt.Sprintf(" %-s |\n", padText(c.name, usageWidth)))
+		}
+	}
+	w.WriteString(fmt.Sprintf(
+		"+%s+%s+%s+\n",
+		strings.Repeat("-", nameWidth+2),
+		strings.Repeat("-", descriptionWidth+2),
+		strings.Repeat("-", usageWidth+2),
+	))
+
+	fmt.Println(w.String())
+
+	return nil
+}
+
+// Quit gracefully and offload data to other nodes
+func quit(_ string) error {
+	fmt.Println("Quitting...")
+	if joined {
+		// Ask the node itself, since its links change in the background
+		dump, err := localNode.Dump(context.Background(), localNode.Address)
+		if err != nil {
+			return fmt.Errorf("getting dump info: %v", err)
+		}
+		if dump.Successor == localNode.Address {
+			fmt.Print(ansiWrap(`
+Last node in ring
+Data will be lost on quit
+Are you sure? (y/n) `,
+				ansiColors["yellow"],
+			))
+			scanner := bufio.NewScanner(os.Stdin)
+			scanner.Scan()
+			switch {
+			case scanner.Text() == "y":
+				fmt.Println("Ring terminated")
+			default:
+				return errors.New("quit aborted")
+			}
+		}
+		// Offload all keys
+		if err := localNode.Leave(context.Background()); err != nil {
+			// Will not actually quit; let user handle
+			return fmt.Errorf("leaving ring: %v", err)
+		}
+	}
+	fmt.Println(ansiWrap("Goodbye!", ansiColors["cyan"]))
+	os.Exit(0)
+	return nil
+}
+
+func setLogging(input string) error {
+	if words := strings.Fields(input); len(words) == 1 {
+		val, err := strconv.ParseBool(input)
+		if err != nil {
+			return fmt.Errorf("bad value: %v", err)
+		}
+
+		logging = val
+		if val {
+			fmt.Println("Logging turned ON")
+		} else {
+			fmt.Println("Logging turned OFF")
+		}
+	} else {
+		return fmt.Errorf("wrong number of arguments: %s", commands["log"].usage)
+	}
+	return nil
+}
+
+// Change port to listen on, can't be done after joining
+func changePort(p string) error {
+	if !joined {
+		newPort, err := strconv.Atoi(p)
+		if err != nil {
+			return fmt.Errorf("bad port: %v", err)
+		}
+		fmt.Printf("Listening port changed from %d to %d\n", config.Port, newPort)
+		config.Port = newPort
+	} else {
+		return errors.New("can't change port. already listening")
+	}
+	return nil
+}
+
+// Change the port JSON-RPC is served on, can't be done after joining
+func changeJSONRPCPort(p string) error {
+	if joined {
+		return errors.New("can't change JSON-RPC port. already listening")
+	}
+	newPort, err := strconv.Atoi(p)
+	if err != nil {
+		return fmt.Errorf("bad port: %v", err)
+	}
+	config.JSONRPCPort = newPort
+	if newPort == 0 {
+		fmt.Println("JSON-RPC turned OFF")
+	} else {
+		fmt.Printf("JSON-RPC will be served on port %d\n", newPort)
+	}
+	return nil
+}
+
+// Change the port the HTTP gateway is served on, can't be done after joining
+func changeHTTPPort(p string) error {
+	if joined {
+		return errors.New("can't change HTTP port. already listening")
+	}
+	newPort, err := strconv.Atoi(p)
+	if err != nil {
+		return fmt.Errorf("bad port: %v", err)
+	}
+	config.HTTPPort = newPort
+	if newPort == 0 {
+		fmt.Println("HTTP gateway turned OFF")
+	} else {
+		fmt.Printf("HTTP gateway will be served on port %d\n", newPort)
+	}
+	return nil
+}
+
+// Change the deadlines used by RPCs and lookups, can't be done after joining or connecting
+func setTimeouts(input string) error {
+	if ring != nil {
+		return errors.New("can't change timeouts. already using a ring")
+	}
+	if words := strings.Fields(input); len(words) == 2 {
+		callTimeout, err := time.ParseDuration(words[0])
+		if err != nil {
+			return fmt.Errorf("bad call timeout: %v", err)
+		}
+		lookupTimeout, err := time.ParseDuration(words[1])
+		if err != nil {
+			return fmt.Errorf("bad lookup timeout: %v", err)
+		}
+		config.CallTimeout, config.LookupTimeout = callTimeout, lookupTimeout
+		fmt.Printf("Calls time out after %v, lookups after %v\n", callTimeout, lookupTimeout)
+	} else {
+		return fmt.Errorf("wrong number of arguments: %s", commands["timeout"].usage)
+	}
+	return nil
+}
+
+func setDataDir(input string) error {
+	if ring != nil {
+		return errors.New("can't change data directory. already using a ring")
+	}
+	words := strings.Fields(input)
+	if len(words) < 1 {
+		return fmt.Errorf("wrong number of arguments: %s", commands["datadir"].usage)
+	}
+	options, err := parseOptions(words[1:], "sync")
+	if err != nil {
+		return err
+	}
+	var storageOptions chord.FileStorageOptions
+	switch options["sync"] {
+	case "", "always":
+		storageOptions.Sync = chord.SyncAlways
+	case "interval":
+		storageOptions.Sync = chord.SyncInterval
+	case "never":
+		storageOptions.Sync = chord.SyncNever
+	default:
+		return fmt.Errorf("bad sync policy %q: expected always, interval or never", options["sync"])
+	}
+	storage, err := chord.NewFileStorage(words[0], storageOptions)
+	if err != nil {
+		return err
+	}
+	if config.Storage != nil {
+		config.Storage.Close()
+	}
+	config.Storage = storage
+	config.DataDir = words[0]
+	count, _ := storage.Count()
+	fmt.Printf("Keeping items in %s, %d loaded\n", words[0], count)
+	return nil
+}
+
+func ping(inputAddress string) error {
+	address, err := validateAddress((inputAddress))
+	if err != nil {
+		return fmt.Errorf("bad address: %v", err)
+	}
+	fmt.Printf("Attempting to ping %s...\n", address)
+	if err := chord.Ping(context.Background(), address); err != nil {
+		return fmt.Errorf("ping: %v", err)
+	}
+	fmt.Println("Success")
+	return nil
+}
+
+func create(_ string) error {
+	if !joined {
+		var err error
+		if localNode, err = chord.Create(config); err != nil {
+			forgetStorage()
+			return fmt.Errorf("creating ring: %v", err)
+		}
+		// Successful creation of new ring
+		joined = true
+		ring = localNode
+		fmt.Printf("Local Address: %s\n", localNode.Address)
+	} else {
+		return errors.New("can't create ring. already part of a ring")
+	}
+	return nil
+}
+
+func join(inputAddress string) error {
+	if !joined {
+		address, err := validateAddress(inputAddress)
+		if err != nil {
+			return fmt.Errorf("bad address: %v", err)
+		}
+		if localNode, err = chord.Join(config, address); err != nil {
+			forgetStorage()
+			return fmt.Errorf("joining ring: %v", err)
+		}
+		// Successful join
+		joined = true
+		ring = localNode
+		fmt.Printf("Local Address: %s\n", localNode.Address)
+	} else {
+		return errors.New("can't join ring. already part of a ring")
+	}
+	return nil
+}
+
+func restart(_ string) error {
+	if joined {
+		return errors.New("can't restart. already part of a ring")
+	}
+	if config.DataDir == "" {
+		return fmt.Errorf("no data directory: %s", commands["datadir"].usage)
+	}
+	var err error
+	if localNode, err = chord.Restart(config); err != nil {
+		forgetStorage()
+		return fmt.Errorf("restarting: %v", err)
+	}
+	joined = true
+	ring = localNode
+	fmt.Printf("Local Address: %s\n", localNode.Address)
+	return nil
+}
+
+// A node that fails to start closes its storage, so open it again from the data directory next time
+func forgetStorage() {
+	config.Storage = nil
+}
+
+// Connect to a ring as a client through one or more seed nodes
+func connect(input string) error {
+	if joined {
+		return errors.New("can't connect to ring. already part of a ring")
+	}
+	seeds := []chord.Address{}
+	for _, word := range strings.Fields(input) {
+		address, err := validateAddress(word)
+		if err != nil {
+			return fmt.Errorf("bad address: %v", err)
+		}
+		seeds = append(seeds, address)
+	}
+	client, err := chord.NewClient(config, seeds...)
+	if err != nil {
+		return fmt.Errorf("wrong number of arguments: %s", commands["connect"].usage)
+	}
+	ring = client
+	fmt.Printf("Connected through %d seed node(s)\n", len(seeds))
+	return nil
+}
+
+// Dump info on local node
+func dumpCurrent(_ string) error {
+	dump, err := localNode.Dump(context.Background(), localNode.Address)
+	if err != nil {
+		return fmt.Errorf("getting dump info: %v", err)
+	}
+	fmt.Println(dump.Dump)
+	return nil
+}
+
+// Dumps info on the node responsible for a key
+func dumpKey(input string) error {
+	if words := strings.Fields(input); len(words) == 1 {
+		key := keyOf(words[0])
+		fmt.Printf("Get item with key: %s\n", key)
+		// Find address to get from
+		address, err := ring.Lookup(context.Background(), key)
+		if err != nil {
+			return fmt.Errorf("finding node with key: %v", err)
+		}
+		// Get dump info
+		dump, err := ring.Dump(context.Background(), address)
+		if err != nil {
+			return fmt.Errorf("getting dump info: %v", err)
+		}
+		fmt.Println(dump.Dump)
+	} else {
+		return fmt.Errorf("wrong number of arguments: %s", commands["dumpkey"].usage)
+	}
+	return nil
+}
+
+// Dumps info on the node at the requested address
+func dumpAddress(inputAddress string) error {
+	address, err := validateAddress(inputAddress)
+	if err != nil {
+		return fmt.Errorf("bad address: %v", err)
+	}
+	// Get dump info
+	dump, err := chord.Dump(context.Background(), address)
+	if err != nil {
+		return fmt.Errorf("getting dump info: %v", err)
+	}
+	fmt.Println(dump.Dump)
+	return nil
+}
+
+// Dumps info on each node in the current ring
+func dumpAll(_ string) error {
+	// First print current node
+	dump, err := localNode.Dump(context.Background(), localNode.Address)
+	if err != nil {
+		return fmt.Errorf("getting dump info: %v", err)
+	}
+	fmt.Println("Current Node:")
+	fmt.Println(dump.Dump)
+
+	for dump.Successor != localNode.Address {
+		// Now get the value
+		if dump, err = localNode.Dump(context.Background(), dump.Successor); err != nil {
+			return fmt.Errorf("getting dump info: %v", err)
+		}
+		// Separator
+		fmt.Println(strings.Repeat("=", 50) + "\n")
+		fmt.Println(dump.Dump)
+	}
+	return nil
+}
+
+func put(input string) error {
+	if words := strings.Fields(input); len(words) >= 2 {
+		key, value := keyOf(words[0]), words[1]
+		options, err := parseOptions(words[2:], "n", "w", "ttl")
+		if err != nil {
+			return err
+		}
+		consistency, err := consistencyOf(options)
+		if err != nil {
+			return err
+		}
+		var ttl time.Duration
+		if options["ttl"] != "" {
+			if ttl, err = time.ParseDuration(options["ttl"]); err != nil || ttl <= 0 {
+				return fmt.Errorf("bad ttl: expected a positive duration such as 30s")
+			}
+		}
+		fmt.Printf("Put: %s => %s\n", key, value)
+		if err := ring.PutTTL(context.Background(), key, value, ttl, consistency); err != nil {
+			return fmt.Errorf("put error: %v", err)
+		}
+	} else {
+		return fmt.Errorf("wrong number of arguments: %s", commands["put"].usage)
+	}
+	return nil
+}
+
+func get(input string) error {
+	if words := strings.Fields(input); len(words) >= 1 {
+		key := keyOf(words[0])
+		consistency, err := parseConsistency(words[1:], "n", "r")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Get item with key: %s\n", key)
+		siblings, err := ring.GetVersions(context.Background(), key, consistency)
+		if err != nil {
+			return err
+		}
+		if len(siblings) > 1 {
+			fmt.Printf("%d conflicting versions, put a new value to replace them\n", len(siblings))
+		}
+		for _, item := range siblings {
+			fmt.Printf("%s (%s)\n", chord.KeyValue{Key: key, Value: item.Value}, item.Version)
+		}
+	} else {
+		return fmt.Errorf("wrong number of arguments: %s", commands["get"].usage)
+	}
+	return nil
+}
+
+func deleteKey(input string) error {
+	if words := strings.Fields(input); len(words) >= 1 {
+		key := keyOf(words[0])
+		consistency, err := parseConsistency(words[1:], "n", "w")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Delete item with key: %s\n", key)
+		value, err := ring.DeleteWith(context.Background(), key, consistency)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Successfully deleted item with key: %s, value: %s\n", key, value)
+	} else {
+		return fmt.Errorf("wrong number of arguments: %s", commands["delete"].usage)
+	}
+	return nil
+}
+
+func compareAndSwap(input string) error {
+	if words := strings.Fields(input); len(words) >= 3 {
+		key, expected, value := keyOf(words[0]), words[1], words[2]
+		consistency, err := parseConsistency(words[3:], "n", "w")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Swap: %s => %s, expecting %s\n", key, value, expected)
+		condition := chord.Condition{Value: &expected}
+		if err := ring.PutIf(context.Background(), key, value, condition, consistency); err == chord.ErrConditionFailed {
+			return fmt.Errorf("not swapped: %s does not have the value %s", key, expected)
+		} else if err != nil {
+			return fmt.Errorf("cas error: %v", err)
+		}
+	} else {
+		return fmt.Errorf("wrong number of arguments: %s", commands["cas"].usage)
+	}
+	return nil
+}
+
+func putIfAbsent(input string) error {
+	if words := strings.Fields(input); len(words) >= 2 {
+		key, value := keyOf(words[0]), words[1]
+		consistency, err := parseConsistency(words[2:], "n", "w")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Put if absent: %s => %s\n", key, value)
+		condition := chord.Condition{Absent: true}
+		if err := ring.PutIf(context.Background(), key, value, condition, consistency); err == chord.ErrConditionFailed {
+			return fmt.Errorf("not stored: %s already exists", key)
+		} else if err != nil {
+			return fmt.Errorf("putnx error: %v", err)
+		}
+	} else {
+		return fmt.Errorf("wrong number of arguments: %s", commands["putnx"].usage)
+	}
+	return nil
+}
+
+func multiPut(input string) error {
+	words := strings.Fields(input)
+	if len(words) == 0 || len(words)%2 != 0 {
+		return fmt.Errorf("wrong number of arguments: %s", commands["mput"].usage)
+	}
+	items := make(map[chord.Key]string)
+	for i := 0; i < len(words); i += 2 {
+		items[keyOf(words[i])] = words[i+1]
+	}
+	fmt.Printf("Put %d items\n", len(items))
+	if err := ring.MultiPut(context.Background(), items, chord.Consistency{}); err != nil {
+		return batchFailures("mput", err)
+	}
+	return nil
+}
+
+func multiGet(input string) error {
+	words := strings.Fields(input)
+	if len(words) == 0 {
+		return fmt.Errorf("wrong number of arguments: %s", commands["mget"].usage)
+	}
+	keys := []chord.Key{}
+	for _, word := range words {
+		keys = append(keys, keyOf(word))
+	}
+	items, err := ring.MultiGet(context.Background(), keys, chord.Consistency{})
+	for _, key := range keys {
+		if siblings, found := items[key]; found {
+			fmt.Println(chord.KeyValue{Key: key, Value: []byte(siblings.String())})
+		} else if batchErr, ok := err.(*chord.BatchError); !ok || batchErr.Errors[key] == nil {
+			fmt.Printf("%-20s    not found\n", key)
+		}
+	}
+	if err != nil {
+		return batchFailures("mget", err)
+	}
+	return nil
+}
+
+func putFile(input string) error {
+	words := strings.Fields(input)
+	if len(words) != 2 {
+		return fmt.Errorf("wrong number of arguments: %s", commands["putfile"].usage)
+	}
+	key, path := keyOf(words[0]), words[1]
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Put: %s => %s (%d bytes)\n", key, path, len(data))
+	if err := ring.PutLarge(context.Background(), key, data, chord.Consistency{}); err != nil {
+		return fmt.Errorf("putfile error: %v", err)
+	}
+	return nil
+}
+
+func getFile(input string) error {
+	words := strings.Fields(input)
+	if len(words) != 2 {
+		return fmt.Errorf("wrong number of arguments: %s", commands["getfile"].usage)
+	}
+	key, path := keyOf(words[0]), words[1]
+	data, err := ring.GetLarge(context.Background(), key, chord.Consistency{})
+	if err != nil {
+		return fmt.Errorf("getfile error: %v", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Got: %s => %s (%d bytes)\n", key, path, len(data))
+	return nil
+}
+
+func watchKey(input string) error {
+	words := strings.Fields(input)
+	if len(words) != 1 {
+		return fmt.Errorf("wrong number of arguments: %s", commands["watch"].usage)
+	}
+	key := keyOf(words[0])
+	if _, exists := watches[key]; exists {
+		return fmt.Errorf("already watching %s", string(key))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	watches[key] = cancel
+	// Events arrive while the prompt waits for input, so each is printed on its own line followed by a new prompt
+	go ring.Watch(ctx, key, func(event chord.WatchEvent) {
+		if event.Deleted {
+			fmt.Print(ansiWrap(fmt.Sprintf("\nwatch: %s deleted", key), ansiColors["cyan"]) + "\n>>> ")
+			return
+		}
+		for _, item := range event.Siblings {
+			line := fmt.Sprintf("\nwatch: %s (%s)", chord.KeyValue{Key: key, Value: item.Value}, item.Version)
+			fmt.Print(ansiWrap(line, ansiColors["cyan"]))
+		}
+		fmt.Print("\n>>> ")
+	})
+	fmt.Printf("Watching %s\n", key)
+	return nil
+}
+
+func unwatchKey(input string) error {
+	words := strings.Fields(input)
+	if len(words) != 1 {
+		return fmt.Errorf("wrong number of arguments: %s", commands["unwatch"].usage)
+	}
+	key := keyOf(words[0])
+	cancel, exists := watches[key]
+	if !exists {
+		return fmt.Errorf("not watching %s", string(key))
+	}
+	cancel()
+	delete(watches, key)
+	return nil
+}
+
+func listKeys(input string) error {
+	words := strings.Fields(input)
+	if len(words) > 1 {
+		return fmt.Errorf("wrong number of arguments: %s", commands["keys"].usage)
+	}
+	pattern := ""
+	if len(words) == 1 {
+		pattern = words[0]
+	}
+	scan := ring.Scan
+	if bucketName != "" {
+		bucket, err := ring.Bucket(bucketName)
+		if err != nil {
+			return err
+		}
+		scan = bucket.Keys
+	}
+	count := 0
+	cursor := ""
+	for {
+		page, err := scan(context.Background(), cursor, pattern, 0)
+		if err != nil {
+			return fmt.Errorf("keys error: %v", err)
+		}
+		for _, key := range page.Keys {
+			// Bucket keys come back without the bucket, which the displayed hash needs
+			fmt.Println(keyOf(string(key)))
+		}
+		count += len(page.Keys)
+		if cursor = page.Cursor; cursor == "" {
+			break
+		}
+	}
+	fmt.Printf("%d keys\n", count)
+	return nil
+}
+
+func useBucket(input string) error {
+	words := strings.Fields(input)
+	if len(words) > 1 {
+		return fmt.Errorf("wrong number of arguments: %s", commands["bucket"].usage)
+	}
+	if len(words) == 0 {
+		bucketName = ""
+		fmt.Println("Using plain keys")
+		return nil
+	}
+	if _, err := ring.Bucket(words[0]); err != nil {
+		return err
+	}
+	bucketName = words[0]
+	fmt.Printf("Using bucket %s\n", bucketName)
+	return nil
+}
+
+func listBuckets(_ string) error {
+	buckets, err := ring.Buckets(context.Background())
+	if err != nil {
+		return fmt.Errorf("buckets error: %v", err)
+	}
+	names := []string{}
+	for name := range buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%-20s %8d keys %10d bytes\n", name, buckets[name].Keys, buckets[name].Bytes)
+	}
+	fmt.Printf("%d buckets\n", len(names))
+	return nil
+}
+
+func dropBucket(input string) error {
+	words := strings.Fields(input)
+	if len(words) != 1 {
+		return fmt.Errorf("wrong number of arguments: %s", commands["dropbucket"].usage)
+	}
+	bucket, err := ring.Bucket(words[0])
+	if err != nil {
+		return err
+	}
+	deleted, err := bucket.Drop(context.Background())
+	fmt.Printf("Deleted %d keys from %s\n", deleted, bucket.Name)
+	if err != nil {
+		return fmt.Errorf("dropbucket error: %v", err)
+	}
+	return nil
+}
+
+func putRandom(input string) error {
+	count, err := strconv.Atoi(input)
+	if err != nil {
+		return fmt.Errorf("bad number: %v", err)
+	}
+	items := make(map[chord.Key]string)
+	for len(items) < count {
+		items[keyOf(randomString(5))] = randomString(5)
+	}
+	if err := ring.MultiPut(context.Background(), items, chord.Consistency{}); err != nil {
+		return batchFailures("put", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/evad1n/chord"
+)
+
+// Get local IP address
+func getLocalAddress() string {
+	conn, err := net.Dial("udp", "8.8.8.8:80")
+	if err != nil {
+		log.Fatalf("looking up IP address: %v", err)
+	}
+	defer conn.Close()
+
+	localAddr := conn.LocalAddr().(*net.UDPAddr)
+
+	return localAddr.IP.String()
+}
+
+// Wrap some text in an ansi code
+func ansiWrap(text string, code string) string {
+	return fmt.Sprintf("%s%s\x1b[0m", code, text)
+}
+
+// Centers text in the middle of a column of size {size}
+func centerText(text string, size int, fill rune) string {
+	if &fill == nil {
+		fill = ' '
+	}
+	size -= len(text)
+	front := size / 2
+	return strings.Repeat(string(fill), front) + text + strings.Repeat(string(fill), size-front)
+}
+
+// Pads string with spaces for desired length
+func padText(text string, desiredlength int) string {
+	for len(text) < desiredlength {
+		text += " "
+	}
+	return text
+}
+
+// Validate an address (host IP + port)
+func validateAddress(address string) (chord.Address, error) {
+	// Regex for <IPv4>:<PORT>
+	matched, _ := regexp.Match(`^(?:\d+\.){3}\d+:(?:\d?){4}\d$`, []byte(address))
+	if matched {
+		return chord.Address(address), nil
+	}
+	return chord.Address(address), errors.New("invalid address format: <host>:<port>")
+}
+
+// Returns a random string of the specified length
+func randomString(length int) string {
+	runes := []rune{}
+	for i := 0; i < length; i++ {
+		// 97-122 range of alphabet characters
+		r := rand.Intn(26) + 97
+		runes = append(runes, rune(r))
+	}
+	return string(runes)
+}
+
+// Parse options of the form <name>=<value>, only allowing the given names
+func parseOptions(words []string, allowed ...string) (map[string]string, error) {
+	options := make(map[string]string)
+	for _, word := range words {
+		parts := strings.SplitN(word, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("bad option %q: expected <name>=<value>", word)
+		}
+		name := strings.ToLower(parts[0])
+		known := false
+		for _, a := range allowed {
+			known = known || name == a
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown option %q: expected one of %s", name, strings.Join(allowed, ", "))
+		}
+		options[name] = parts[1]
+	}
+	return options, nil
+}
+
+// Parse per-request consistency overrides (n=, r= and w=)
+func parseConsistency(words []string, allowed ...string) (chord.Consistency, error) {
+	options, err := parseOptions(words, allowed...)
+	if err != nil {
+		return chord.Consistency{}, err
+	}
+	return consistencyOf(options)
+}
+
+// Read the consistency overrides from parsed options, ignoring any other options
+func consistencyOf(options map[string]string) (chord.Consistency, error) {
+	var consistency chord.Consistency
+	fields := map[string]*int{
+		"n": &consistency.N,
+		"r": &consistency.R,
+		"w": &consistency.W,
+	}
+	for name, value := range options {
+		field, exists := fields[name]
+		if !exists {
+			continue
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil || count <= 0 {
+			return consistency, fmt.Errorf("bad %s: must be a positive number", name)
+		}
+		*field = count
+	}
+	return consistency, nil
+}
+
+// The ring key for a key typed at the prompt, in the current bucket if one is set
+func keyOf(word string) chord.Key {
+	if bucketName == "" {
+		return chord.Key(word)
+	}
+	bucket, _ := ring.Bucket(bucketName)
+	return bucket.Key(word)
+}
+
+// List the keys a batched operation failed for, one per line
+func batchFailures(operation string, err error) error {
+	batchErr, ok := err.(*chord.BatchError)
+	if !ok {
+		return fmt.Errorf("%s error: %v", operation, err)
+	}
+	keys := []string{}
+	for key := range batchErr.Errors {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("%-20s    failed: %v\n", chord.Key(key), batchErr.Errors[chord.Key(key)])
+	}
+	return fmt.Errorf("%s error: %d keys failed", operation, len(keys))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/evad1n/chord"
+)
+
+var (
+	config    = chord.DefaultConfig() // Settings for the local node
+	localNode *chord.Node             // The local node, only set after join/creation
+	joined    = false                 // Whether this node is part of a ring yet
+	ring      ringClient              // How key/value operations reach the ring, set after join/creation/connection
+
+	logging = false // Whether to print log messages
+
+	watches    = make(map[chord.Key]context.CancelFunc) // Stops the watch of each watched key
+	bucketName = ""                                     // The bucket keys typed at the prompt are in, empty for none
+)
+
+// The ring operations shared by a member node and a client
+type ringClient interface {
+	Lookup(ctx context.Context, key chord.Key) (chord.Address, error)
+	Put(ctx context.Context, key chord.Key, value string) error
+	PutTTL(ctx context.Context, key chord.Key, value string, ttl time.Duration, consistency chord.Consistency) error
+	PutIf(ctx context.Context, key chord.Key, value string, condition chord.Condition, consistency chord.Consistency) error
+	GetVersions(ctx context.Context, key chord.Key, consistency chord.Consistency) (chord.Siblings, error)
+	MultiPut(ctx context.Context, items map[chord.Key]string, consistency chord.Consistency) error
+	MultiGet(ctx context.Context, keys []chord.Key, consistency chord.Consistency) (map[chord.Key]chord.Siblings, error)
+	PutLarge(ctx context.Context, key chord.Key, value []byte, consistency chord.Consistency) error
+	GetLarge(ctx context.Context, key chord.Key, consistency chord.Consistency) ([]byte, error)
+	Watch(ctx context.Context, key chord.Key, handle func(chord.WatchEvent)) error
+	Bucket(name string) (*chord.Bucket, error)
+	Buckets(ctx context.Context) (map[string]chord.BucketStats, error)
+	Scan(ctx context.Context, cursor string, pattern string, limit int) (chord.ScanPage, error)
+	DeleteWith(ctx context.Context, key chord.Key, consistency chord.Consistency) (string, error)
+	Dump(ctx context.Context, address chord.Address) (chord.DumpReturn, error)
+}
+
+// A way to color the log yellow
+type myWriter struct {
+	w io.Writer
+}
+
+func (w myWriter) Write(p []byte) (n int, err error) {
+	if !logging {
+		return
+	}
+	w.w.Write([]byte(ansiColors["yellow"]))
+	n, err = w.w.Write(p)
+	w.w.Write([]byte("\x1b[0m"))
+	return
+}
+
+func main() {
+	// Setup
+	rand.Seed(time.Now().Unix())
+	createMaps()
+	defaultCommands()
+
+	// DEBUGGING
+	log.SetFlags(log.Lshortfile)
+	log.SetOutput(myWriter{os.Stdout})
+
+	fmt.Print("Welcome to the CHORD distributed hash table(DHT)\n\n")
+
+	config.AdvertiseAddress = getLocalAddress()
+	fmt.Printf("Current address: %s\n", config.AdvertiseAddress)
+	fmt.Printf("Current port: %d\n", config.Port)
+	if logging {
+		fmt.Println("Logging is turned ON")
+	} else {
+		fmt.Println("Logging is turned OFF")
+	}
+	fmt.Println()
+
+	commandLoop()
+}
+
+func commandLoop() {
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Print(">>> ")
+	for scanner.Scan() {
+		// Otherwise process commands
+		if words := strings.Fields(scanner.Text()); len(words) > 0 {
+			// Check if cmd exists
+			if cmd, exists := commands[strings.ToLower(words[0])]; exists {
+				params := strings.Join(words[1:], " ")
+				switch {
+				case !joined && cmd.joinRequired:
+					fmt.Println(ansiWrap("must join a ring for this command", ansiColors["red"]))
+				case ring == nil && cmd.connectRequired:
+					fmt.Println(ansiWrap("must join or connect to a ring for this command", ansiColors["red"]))
+				default:
+					if err := cmd.do(params); err != nil {
+						fmt.Println(ansiWrap(err.Error(), ansiColors["red"]))
+					} else {
+						fmt.Println(ansiWrap("OK", ansiColors["green"]))
+					}
+				}
+			} else {
+				fmt.Println(ansiWrap("Unrecognized command!", ansiColors["red"]))
+			}
+		}
+		// New prompt
+		fmt.Print(">>> ")
+	}
+}
@@ -0,0 +1,140 @@
+package chord
+
+import (
+	"log"
+	"time"
+)
+
+// Config holds the settings used to start a node. Zero fields are replaced by the values from DefaultConfig.
+type Config struct {
+	BindAddress      string // The local interface to listen on, empty for all interfaces
+	AdvertiseAddress string // The host address other nodes use to reach this node
+	Port             int    // The port to listen on, 0 picks a free port
+
+	StabilizeInterval        time.Duration // How often the successor list is maintained
+	FixFingersInterval       time.Duration // How often a finger table entry is refreshed
+	CheckPredecessorInterval time.Duration // How often the predecessor is checked for failure
+	AntiEntropyInterval      time.Duration // How often items are compared with the replicas and differences repaired
+	HintInterval             time.Duration // How often puts held for unreachable owners are retried
+	ExpireInterval           time.Duration // How often expired items and old tombstones are removed from storage
+	TombstoneTTL             time.Duration // How long a delete is remembered so replicas that missed it catch up, far longer than AntiEntropyInterval
+
+	Successors int // The length of the successor list
+	Replicas   int // How many nodes hold each item, the owner plus the first Replicas-1 successors
+
+	MaxValueSize int // The largest value in bytes the node accepts for a key
+	ChunkSize    int // The size of the chunks PutLarge splits a value into, no larger than MaxValueSize of the nodes
+
+	ChunkGrace time.Duration // How long a chunk no manifest lists is kept before it is collected, longer than a PutLarge takes
+
+	ReadQuorum  int // How many replicas must reply to a read by default, counting the owner
+	WriteQuorum int // How many replicas must acknowledge a write by default, counting the owner
+
+	CallTimeout   time.Duration // The deadline for a single RPC, including connecting
+	LookupTimeout time.Duration // The deadline for a whole lookup across the ring
+
+	Logger *log.Logger // Where log messages are written
+
+	Transport Transport // How calls reach other nodes, nil uses NewRPCTransport
+	Storage   Storage   // Where the node keeps its items, nil uses NewFileStorage in DataDir or else NewMemoryStorage. Every node needs its own.
+
+	DataDir string // Where the node saves its place on the ring so it can Restart, empty to save nothing
+
+	JSONRPCPort int // The port to also serve the NodeActor methods on with JSON-RPC, 0 disables it
+	HTTPPort    int // The port to serve the REST gateway on, 0 disables it
+}
+
+// DefaultConfig returns the settings used by a stock node
+func DefaultConfig() Config {
+	return Config{
+		AdvertiseAddress: "127.0.0.1",
+		Port:             3400,
+
+		StabilizeInterval:        time.Second,
+		FixFingersInterval:       time.Second,
+		CheckPredecessorInterval: time.Second,
+		AntiEntropyInterval:      30 * time.Second,
+		HintInterval:             5 * time.Second,
+		ExpireInterval:           10 * time.Second,
+		TombstoneTTL:             24 * time.Hour,
+
+		Successors: 5,
+		Replicas:   3,
+
+		MaxValueSize: 1 << 20,
+		ChunkSize:    256 << 10,
+
+		ChunkGrace: 10 * time.Minute,
+
+		ReadQuorum:  1,
+		WriteQuorum: 1,
+
+		CallTimeout:   2 * time.Second,
+		LookupTimeout: 10 * time.Second,
+
+		Logger: log.Default(),
+	}
+}
+
+// Fill in any unset fields with defaults. Port is left alone since 0 is meaningful.
+func (cfg Config) withDefaults() Config {
+	def := DefaultConfig()
+	if cfg.AdvertiseAddress == "" {
+		cfg.AdvertiseAddress = cfg.BindAddress
+	}
+	if cfg.AdvertiseAddress == "" {
+		cfg.AdvertiseAddress = def.AdvertiseAddress
+	}
+	if cfg.StabilizeInterval <= 0 {
+		cfg.StabilizeInterval = def.StabilizeInterval
+	}
+	if cfg.FixFingersInterval <= 0 {
+		cfg.FixFingersInterval = def.FixFingersInterval
+	}
+	if cfg.CheckPredecessorInterval <= 0 {
+		cfg.CheckPredecessorInterval = def.CheckPredecessorInterval
+	}
+	if cfg.AntiEntropyInterval <= 0 {
+		cfg.AntiEntropyInterval = def.AntiEntropyInterval
+	}
+	if cfg.HintInterval <= 0 {
+		cfg.HintInterval = def.HintInterval
+	}
+	if cfg.ExpireInterval <= 0 {
+		cfg.ExpireInterval = def.ExpireInterval
+	}
+	if cfg.TombstoneTTL <= 0 {
+		cfg.TombstoneTTL = def.TombstoneTTL
+	}
+	if cfg.Successors <= 0 {
+		cfg.Successors = def.Successors
+	}
+	if cfg.Replicas <= 0 {
+		cfg.Replicas = def.Replicas
+	}
+	if cfg.MaxValueSize <= 0 {
+		cfg.MaxValueSize = def.MaxValueSize
+	}
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = def.ChunkSize
+	}
+	if cfg.ChunkGrace <= 0 {
+		cfg.ChunkGrace = def.ChunkGrace
+	}
+	if cfg.ReadQuorum <= 0 {
+		cfg.ReadQuorum = def.ReadQuorum
+	}
+	if cfg.WriteQuorum <= 0 {
+		cfg.WriteQuorum = def.WriteQuorum
+	}
+	if cfg.CallTimeout <= 0 {
+		cfg.CallTimeout = def.CallTimeout
+	}
+	if cfg.LookupTimeout <= 0 {
+		cfg.LookupTimeout = def.LookupTimeout
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = def.Logger
+	}
+	return cfg
+}
@@ -0,0 +1,272 @@
+package chord
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The file storage engine keeps every item in memory and makes it durable with two files in a directory:
+// a snapshot of all items, and a write-ahead log with one JSON record per put or delete since the snapshot.
+// On startup the snapshot is loaded and the log replayed on top of it. The log is compacted every so often,
+// and sooner once it holds enough records: a new snapshot is written next to the old one, renamed over it,
+// and the log emptied.
+
+const (
+	snapshotFile = "snapshot.json"
+	walFile      = "wal.log"
+)
+
+// SyncPolicy sets when writes to the log are flushed to disk
+type SyncPolicy int
+
+const (
+	SyncAlways   SyncPolicy = iota // Flush after every write, nothing acknowledged is lost
+	SyncInterval                   // Flush every SyncInterval, a crash loses at most that much
+	SyncNever                      // Leave flushing to the operating system
+)
+
+type (
+	// FileStorageOptions tune a file storage engine. Zero fields use the defaults.
+	FileStorageOptions struct {
+		Sync            SyncPolicy    // When the log is flushed to disk, SyncAlways by default
+		SyncInterval    time.Duration // How often the log is flushed with SyncInterval, 1s by default
+		CompactInterval time.Duration // How often the log is compacted into the snapshot if it holds any records, 1m by default
+		CompactAfter    int           // How many log records trigger a compaction before the interval is up, 10000 by default
+	}
+
+	// Storage engine that keeps items in memory, backed by a snapshot and a write-ahead log
+	fileStorage struct {
+		mu      sync.Mutex // Guards the log against the background flushing and compaction
+		dir     string
+		options FileStorageOptions
+		memory  *memoryStorage
+		wal     *os.File
+		records int           // Records in the log since the last compaction
+		done    chan struct{} // Closed to stop the background flushing and compaction
+		closed  sync.Once     // Close only releases the engine once
+		closing error         // What the first Close returned
+	}
+
+	// One put or delete in the write-ahead log
+	walRecord struct {
+		Delete   bool     `json:"delete,omitempty"`
+		Key      Key      `json:"key"`
+		Siblings Siblings `json:"siblings,omitempty"`
+	}
+)
+
+// NewFileStorage opens a storage engine that keeps its items in a directory, creating it if needed.
+// Items already in the directory are loaded, so a node restarted with the same directory keeps its data.
+func NewFileStorage(dir string, options FileStorageOptions) (Storage, error) {
+	if options.SyncInterval <= 0 {
+		options.SyncInterval = time.Second
+	}
+	if options.CompactInterval <= 0 {
+		options.CompactInterval = time.Minute
+	}
+	if options.CompactAfter <= 0 {
+		options.CompactAfter = 10000
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating storage directory: %v", err)
+	}
+	s := &fileStorage{
+		dir:     dir,
+		options: options,
+		memory:  &memoryStorage{items: make(map[Key]Siblings)},
+		done:    make(chan struct{}),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening log: %v", err)
+	}
+	s.wal = wal
+	// Start from a clean log, which also drops any torn record left by a crash
+	if s.records > 0 {
+		if err := s.compact(); err != nil {
+			wal.Close()
+			return nil, err
+		}
+	}
+	go s.maintain()
+	return s, nil
+}
+
+// Load the snapshot and replay the log on top of it
+func (s *fileStorage) load() error {
+	snapshot, err := os.Open(filepath.Join(s.dir, snapshotFile))
+	if err == nil {
+		defer snapshot.Close()
+		if err := json.NewDecoder(snapshot).Decode(&s.memory.items); err != nil {
+			return fmt.Errorf("reading snapshot: %v", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("opening snapshot: %v", err)
+	}
+
+	wal, err := os.Open(filepath.Join(s.dir, walFile))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("opening log: %v", err)
+	}
+	defer wal.Close()
+	scanner := bufio.NewScanner(wal)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var record walRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// A crash part way through an append leaves a torn last record, which was never acknowledged
+			s.records++
+			break
+		}
+		s.apply(record)
+		s.records++
+	}
+	return scanner.Err()
+}
+
+// Apply a log record to the items in memory
+func (s *fileStorage) apply(record walRecord) {
+	if record.Delete {
+		s.memory.Delete(record.Key)
+	} else {
+		s.memory.Put(record.Key, record.Siblings)
+	}
+}
+
+// Append a record to the log, flush it according to the sync policy, and apply it
+func (s *fileStorage) append(record walRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encoding log record: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.wal.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing log: %v", err)
+	}
+	if s.options.Sync == SyncAlways {
+		if err := s.wal.Sync(); err != nil {
+			return fmt.Errorf("syncing log: %v", err)
+		}
+	}
+	s.apply(record)
+	s.records++
+	if s.records >= s.options.CompactAfter {
+		return s.compact()
+	}
+	return nil
+}
+
+// Write every item to a new snapshot and empty the log. The old snapshot is only replaced once the new one is on disk.
+func (s *fileStorage) compact() error {
+	path := filepath.Join(s.dir, snapshotFile)
+	temp, err := os.Create(path + ".tmp")
+	if err != nil {
+		return fmt.Errorf("creating snapshot: %v", err)
+	}
+	if err := json.NewEncoder(temp).Encode(s.memory.items); err != nil {
+		temp.Close()
+		return fmt.Errorf("writing snapshot: %v", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("syncing snapshot: %v", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("closing snapshot: %v", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("replacing snapshot: %v", err)
+	}
+	if dir, err := os.Open(s.dir); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	// Replaying the old log over the new snapshot is harmless, so a crash before truncating loses nothing
+	if err := s.wal.Truncate(0); err != nil {
+		return fmt.Errorf("emptying log: %v", err)
+	}
+	s.records = 0
+	return nil
+}
+
+// Flush the log to disk every SyncInterval if that is the policy, and compact it every CompactInterval, until the engine is closed.
+// A failed compaction leaves the log as it was, so it is tried again on the next tick.
+func (s *fileStorage) maintain() {
+	compact := time.NewTicker(s.options.CompactInterval)
+	defer compact.Stop()
+	var flush <-chan time.Time
+	if s.options.Sync == SyncInterval {
+		ticker := time.NewTicker(s.options.SyncInterval)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-flush:
+			s.mu.Lock()
+			s.wal.Sync()
+			s.mu.Unlock()
+		case <-compact.C:
+			s.mu.Lock()
+			if s.records > 0 {
+				s.compact()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *fileStorage) Get(key Key) (Siblings, error) {
+	return s.memory.Get(key)
+}
+
+func (s *fileStorage) Put(key Key, siblings Siblings) error {
+	return s.append(walRecord{Key: key, Siblings: siblings})
+}
+
+func (s *fileStorage) Delete(key Key) error {
+	if siblings, _ := s.memory.Get(key); siblings == nil {
+		return nil
+	}
+	return s.append(walRecord{Delete: true, Key: key})
+}
+
+func (s *fileStorage) Range(start, end *big.Int) (map[Key]Siblings, error) {
+	return s.memory.Range(start, end)
+}
+
+func (s *fileStorage) Count() (int, error) {
+	return s.memory.Count()
+}
+
+func (s *fileStorage) Snapshot() (map[Key]Siblings, error) {
+	return s.memory.Snapshot()
+}
+
+// Close compacts the log so the next start only reads the snapshot. Closing again returns the first result.
+func (s *fileStorage) Close() error {
+	s.closed.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closing = s.compact()
+		if err := s.wal.Close(); s.closing == nil && err != nil {
+			s.closing = fmt.Errorf("closing log: %v", err)
+		}
+	})
+	return s.closing
+}
@@ -0,0 +1,177 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openFileStorage(t *testing.T, dir string, options FileStorageOptions) Storage {
+	t.Helper()
+	s, err := NewFileStorage(dir, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestFileStorage(t *testing.T) {
+	s := openFileStorage(t, t.TempDir(), FileStorageOptions{})
+	defer s.Close()
+	testEngine(t, s)
+}
+
+func TestFileStorageReload(t *testing.T) {
+	dir := t.TempDir()
+	item := Item{Value: []byte("v"), Version: Version{"n1", 1, VectorClock{}}}
+	s := openFileStorage(t, dir, FileStorageOptions{})
+	s.Put("kept", Siblings{item})
+	s.Put("deleted", Siblings{item})
+	s.Delete("deleted")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("closing again: %v", err)
+	}
+
+	s = openFileStorage(t, dir, FileStorageOptions{})
+	defer s.Close()
+	if siblings, _ := s.Get("kept"); !siblings.equal(Siblings{item}) {
+		t.Errorf("after reopening: kept holds %v", siblings)
+	}
+	if siblings, _ := s.Get("deleted"); siblings != nil {
+		t.Errorf("after reopening: deleted holds %v", siblings)
+	}
+}
+
+func TestFileStorageReplaysLog(t *testing.T) {
+	dir := t.TempDir()
+	item := Item{Value: []byte("v"), Version: Version{"n1", 1, VectorClock{}}}
+	// Left open, as if the process crashed
+	s := openFileStorage(t, dir, FileStorageOptions{})
+	for i := 0; i < 10; i++ {
+		s.Put(Key(fmt.Sprint("key", i)), Siblings{item})
+	}
+	s.Delete("key0")
+	// A record torn part way through an append
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal.WriteString(`{"key":"key10","sib`)
+	wal.Close()
+
+	s = openFileStorage(t, dir, FileStorageOptions{})
+	defer s.Close()
+	if count, _ := s.Count(); count != 9 {
+		t.Errorf("after replaying the log: %d keys", count)
+	}
+	if siblings, _ := s.Get("key0"); siblings != nil {
+		t.Errorf("deleted key holds %v", siblings)
+	}
+	if info, err := os.Stat(filepath.Join(dir, walFile)); err != nil || info.Size() != 0 {
+		t.Errorf("log after replaying: %v, %v", info, err)
+	}
+}
+
+func TestFileStorageCompacts(t *testing.T) {
+	dir := t.TempDir()
+	item := Item{Value: []byte("v"), Version: Version{"n1", 1, VectorClock{}}}
+	s := openFileStorage(t, dir, FileStorageOptions{Sync: SyncNever, CompactAfter: 5})
+	defer s.Close()
+	for i := 0; i < 12; i++ {
+		s.Put(Key(fmt.Sprint("key", i)), Siblings{item})
+	}
+	if records := s.(*fileStorage).records; records != 2 {
+		t.Errorf("%d records in the log after compacting", records)
+	}
+	// The snapshot alone holds the first ten puts
+	data, err := ioutil.ReadFile(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(copied, snapshotFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := openFileStorage(t, copied, FileStorageOptions{})
+	defer snapshot.Close()
+	if count, _ := snapshot.Count(); count != 10 {
+		t.Errorf("the snapshot holds %d keys", count)
+	}
+}
+
+func TestFileStorageCompactsPeriodically(t *testing.T) {
+	dir := t.TempDir()
+	item := Item{Value: []byte("v"), Version: Version{"n1", 1, VectorClock{}}}
+	s := openFileStorage(t, dir, FileStorageOptions{Sync: SyncNever, CompactInterval: 20 * time.Millisecond})
+	defer s.Close()
+	for i := 0; i < 3; i++ {
+		s.Put(Key(fmt.Sprint("key", i)), Siblings{item})
+	}
+	// Far fewer records than CompactAfter are compacted once the interval is up
+	eventually(t, "the log to be compacted", func() bool {
+		info, err := os.Stat(filepath.Join(dir, walFile))
+		return err == nil && info.Size() == 0
+	})
+	data, err := ioutil.ReadFile(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(copied, snapshotFile), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := openFileStorage(t, copied, FileStorageOptions{})
+	defer snapshot.Close()
+	if count, _ := snapshot.Count(); count != 3 {
+		t.Errorf("the snapshot holds %d keys", count)
+	}
+}
+
+func TestFileStorageSyncInterval(t *testing.T) {
+	dir := t.TempDir()
+	item := Item{Value: []byte("v"), Version: Version{"n1", 1, VectorClock{}}}
+	s := openFileStorage(t, dir, FileStorageOptions{Sync: SyncInterval, SyncInterval: 5 * time.Millisecond}).(*fileStorage)
+	// Writes carry on while the log is flushed in the background
+	for i := 0; i < 20; i++ {
+		s.Put(Key(fmt.Sprint("key", i)), Siblings{item})
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	// Stop without compacting, as if the process crashed after the flush
+	close(s.done)
+	s.mu.Lock()
+	s.wal.Close()
+	s.mu.Unlock()
+
+	reopened := openFileStorage(t, dir, FileStorageOptions{})
+	defer reopened.Close()
+	if count, _ := reopened.Count(); count != 20 {
+		t.Errorf("after reopening: %d keys", count)
+	}
+}
+
+func TestStopNodeTwice(t *testing.T) {
+	network := NewMemoryNetwork()
+	dir := t.TempDir()
+	n := testRing(t, network, 1, func(cfg *Config) {
+		cfg.DataDir = dir
+	})[0]
+	client := testClient(t, network, []*Node{n})
+	if err := client.Put(context.Background(), "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	// The ring stops it again when the test ends
+	n.stopNode()
+	s := openFileStorage(t, dir, FileStorageOptions{})
+	defer s.Close()
+	if siblings, _ := s.Get("key"); len(siblings) != 1 || string(siblings[0].Value) != "value" {
+		t.Errorf("after stopping: holds %v", siblings)
+	}
+}
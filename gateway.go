@@ -0,0 +1,166 @@
+package chord
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// The local node's view of the ring, served at /ring
+	ringInfo struct {
+		Address     Address       `json:"address"`
+		Hash        string        `json:"hash"`
+		Predecessor Address       `json:"predecessor"`
+		Successors  []Address     `json:"successors"`
+		Fingers     []fingerEntry `json:"fingers"`
+	}
+
+	// The node responsible for a key, served at /owner/{key}
+	ownerInfo struct {
+		Key   Key     `json:"key"`
+		Hash  string  `json:"hash"`
+		Owner Address `json:"owner"`
+	}
+)
+
+// Serve a REST gateway for key/value operations on a separate port so standard HTTP tools can use the ring.
+// Requests are routed through lookups to the node responsible for each key. Values are stored and read as
+// PutLarge and GetLarge do, so bodies larger than a node accepts are split into chunks.
+//
+//	GET    /keys/{key}  value of a key
+//	PUT    /keys/{key}  store the request body as the value of a key, expiring after ?ttl=<duration> if given
+//	DELETE /keys/{key}  delete a key, returning its value
+//	GET    /ring        this node's successors, predecessor and fingers
+//	GET    /owner/{key} address of the node responsible for a key
+func (n *Node) startGateway() error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(n.config.BindAddress, fmt.Sprint(n.config.HTTPPort)))
+	if err != nil {
+		return fmt.Errorf("HTTP listen error: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/keys/", n.handleKeys)
+	mux.HandleFunc("/ring", n.handleRing)
+	mux.HandleFunc("/owner/", n.handleOwner)
+	n.httpServer = &http.Server{Handler: mux}
+	go n.httpServer.Serve(listener)
+	n.logger.Printf("Serving HTTP gateway on %s", listener.Addr())
+	return nil
+}
+
+func (n *Node) handleKeys(w http.ResponseWriter, r *http.Request) {
+	key := Key(strings.TrimPrefix(r.URL.Path, "/keys/"))
+	if key == "" {
+		http.Error(w, "missing key", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		value, err := n.GetLarge(r.Context(), key, Consistency{})
+		if err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		w.Write(value)
+	case http.MethodPut:
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("reading body: %v", err), http.StatusBadRequest)
+			return
+		}
+		var ttl time.Duration
+		if param := r.URL.Query().Get("ttl"); param != "" {
+			if ttl, err = time.ParseDuration(param); err != nil || ttl <= 0 {
+				http.Error(w, "bad ttl: expected a positive duration such as 30s", http.StatusBadRequest)
+				return
+			}
+		}
+		if err := n.client.putLarge(r.Context(), PutRequest{KeyValue: KeyValue{key, body}, TTL: ttl}); err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		value, err := n.Delete(r.Context(), key)
+		if err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		fmt.Fprint(w, value)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (n *Node) handleRing(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var info ringInfo
+	n.actor.run(func(n *Node) {
+		info = ringInfo{
+			Address:     n.Address,
+			Hash:        fmt.Sprintf("%040x", n.Hash),
+			Predecessor: n.Predecessor,
+			Successors:  append([]Address{}, n.Successors...),
+			Fingers:     n.uniqueFingers(),
+		}
+	})
+	writeJSON(w, info)
+}
+
+func (n *Node) handleOwner(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key := Key(strings.TrimPrefix(r.URL.Path, "/owner/"))
+	if key == "" {
+		http.Error(w, "missing key", http.StatusBadRequest)
+		return
+	}
+	owner, err := n.Lookup(r.Context(), key)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	writeJSON(w, ownerInfo{
+		Key:   key,
+		Hash:  fmt.Sprintf("%040x", key.hashed()),
+		Owner: owner,
+	})
+}
+
+// Report a failed ring operation with a matching status code
+func writeGatewayError(w http.ResponseWriter, err error) {
+	if err == ErrNoSuchKey {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err == ErrValueTooLarge {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err == ErrReservedKey {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := err.(*ConflictError); ok {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,100 @@
+package chord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Send a request straight to a gateway handler of a node
+func serveGateway(n *Node, handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return recorder
+}
+
+func TestGatewayKeys(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	n := nodes[0]
+
+	if r := serveGateway(n, n.handleKeys, http.MethodPut, "/keys/a/b", "hello world"); r.Code != http.StatusNoContent {
+		t.Fatalf("PUT: status %d: %s", r.Code, r.Body)
+	}
+	if r := serveGateway(n, n.handleKeys, http.MethodGet, "/keys/a/b", ""); r.Code != http.StatusOK || r.Body.String() != "hello world" {
+		t.Errorf("GET: status %d: %s", r.Code, r.Body)
+	}
+	if r := serveGateway(n, n.handleKeys, http.MethodDelete, "/keys/a/b", ""); r.Code != http.StatusOK || r.Body.String() != "hello world" {
+		t.Errorf("DELETE: status %d: %s", r.Code, r.Body)
+	}
+	if r := serveGateway(n, n.handleKeys, http.MethodGet, "/keys/a/b", ""); r.Code != http.StatusNotFound {
+		t.Errorf("GET after DELETE: status %d, expected %d", r.Code, http.StatusNotFound)
+	}
+	if r := serveGateway(n, n.handleKeys, http.MethodPut, "/keys/a?ttl=-1s", "x"); r.Code != http.StatusBadRequest {
+		t.Errorf("PUT with a bad ttl: status %d, expected %d", r.Code, http.StatusBadRequest)
+	}
+	if r := serveGateway(n, n.handleKeys, http.MethodPost, "/keys/a", "x"); r.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status %d, expected %d", r.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestGatewayLargeValues(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, func(cfg *Config) {
+		cfg.MaxValueSize = 200
+		cfg.ChunkSize = 200
+	})
+	n := nodes[0]
+	body := strings.Repeat("0123456789", 50)
+
+	// A body larger than a node accepts is split into chunks, and read back whole
+	if r := serveGateway(n, n.handleKeys, http.MethodPut, "/keys/large?ttl=1h", body); r.Code != http.StatusNoContent {
+		t.Fatalf("PUT: status %d: %s", r.Code, r.Body)
+	}
+	if r := serveGateway(n, n.handleKeys, http.MethodGet, "/keys/large", ""); r.Code != http.StatusOK || r.Body.String() != body {
+		t.Errorf("GET: status %d: %s", r.Code, r.Body)
+	}
+	manifest := stored(ownerOf(nodes, "large")[0], "large")
+	if len(manifest) != 1 || !manifest[0].Manifest || manifest[0].Expires.IsZero() {
+		t.Errorf("stored %+v, expected a manifest that expires", manifest)
+	}
+	if _, err := n.Get(context.Background(), "large"); err != ErrLargeValue {
+		t.Errorf("plain get of a large value: %v", err)
+	}
+	if r := serveGateway(n, n.handleKeys, http.MethodDelete, "/keys/large", ""); r.Code != http.StatusOK || r.Body.Len() != 0 {
+		t.Errorf("DELETE: status %d: %s", r.Code, r.Body)
+	}
+	if r := serveGateway(n, n.handleKeys, http.MethodGet, "/keys/large", ""); r.Code != http.StatusNotFound {
+		t.Errorf("GET after DELETE: status %d, expected %d", r.Code, http.StatusNotFound)
+	}
+}
+
+func TestGatewayRing(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	n := byHash(nodes)[0]
+
+	r := serveGateway(n, n.handleRing, http.MethodGet, "/ring", "")
+	var info ringInfo
+	if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+		t.Fatalf("decoding /ring: %v", err)
+	}
+	if info.Address != n.Address || info.Predecessor != byHash(nodes)[2].Address {
+		t.Errorf("ring info %+v", info)
+	}
+	if len(info.Successors) == 0 || info.Successors[0] != byHash(nodes)[1].Address {
+		t.Errorf("successors %v, expected %s first", info.Successors, byHash(nodes)[1].Address)
+	}
+
+	r = serveGateway(n, n.handleOwner, http.MethodGet, "/owner/some-key", "")
+	var owner ownerInfo
+	if err := json.NewDecoder(r.Body).Decode(&owner); err != nil {
+		t.Fatalf("decoding /owner: %v", err)
+	}
+	if expected := ownerOf(nodes, "some-key")[0].Address; owner.Owner != expected {
+		t.Errorf("owner is %s, expected %s", owner.Owner, expected)
+	}
+}
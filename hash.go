@@ -1,4 +1,4 @@
-package main
+package chord
 
 import (
 	"crypto/sha1"
@@ -7,26 +7,32 @@ import (
 )
 
 func (a Address) hashed() *big.Int {
-	return hashString(string(a))
+	return HashString(string(a))
 }
 
 func (a Address) String() string {
-	return fmt.Sprintf("%s [ %s ]", readableHash(a.hashed()), string(a))
+	return fmt.Sprintf("%s [ %s ]", ReadableHash(a.hashed()), string(a))
 }
 
 func (k Key) hashed() *big.Int {
-	return hashString(string(k))
+	return HashString(string(k))
 }
 
+// Keys in a bucket are shown as bucket/key
 func (k Key) String() string {
-	return fmt.Sprintf("%s [ %s ]", readableHash(k.hashed()), string(k))
+	if bucket, name, ok := splitBucket(k); ok {
+		return fmt.Sprintf("%s [ %s/%s ]", ReadableHash(k.hashed()), bucket, name)
+	}
+	return fmt.Sprintf("%s [ %s ]", ReadableHash(k.hashed()), string(k))
 }
 
-func readableHash(hash *big.Int) string {
+// ReadableHash shortens a hash to its first 8 hex digits for display
+func ReadableHash(hash *big.Int) string {
 	return fmt.Sprintf("%040x", hash)[:8] + "..."
 }
 
-func hashString(elt string) *big.Int {
+// HashString computes the SHA-1 hash of a string as a position on the ring
+func HashString(elt string) *big.Int {
 	hasher := sha1.New()
 	hasher.Write([]byte(elt))
 	return new(big.Int).SetBytes(hasher.Sum(nil))
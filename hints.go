@@ -0,0 +1,178 @@
+package chord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Hinted handoff: a put whose owner cannot be reached is handed to another node as a hint tagged with the owner.
+// The node holding the hint keeps pinging the owner and delivers the put once it answers. If the owner has left
+// the ring for good, the hint is delivered to whichever node took over the key.
+//
+// A put that replaces every version only replaces the versions that existed when it was made, so the node
+// accepting the hint records the versions it knows of as the put's context. Writes the owner accepts before
+// the hint arrives are kept as siblings of it rather than overwritten. A node with a data directory saves
+// its hints there, so they survive a restart.
+
+const hintsFile = "hints.json"
+
+var errHintExpired = errors.New("the value's TTL ran out before the owner could take it")
+
+// Hint is a put accepted on behalf of an owner that could not be reached
+type Hint struct {
+	Owner    Address
+	Request  PutRequest
+	Received time.Time // When the hint was accepted, since a TTL counts from then
+}
+
+// Whether a failed call means the node could not be reached, rather than that it refused the request
+func unreachable(ctx context.Context, err error) bool {
+	_, refused := err.(rpc.ServerError)
+	return err != nil && !refused && err != ErrNoSuchKey && err != ErrConditionFailed && err != ErrValueTooLarge && ctx.Err() == nil
+}
+
+// Whether a failed put should be handed off as a hint. Only unconditional puts that need no acknowledgements
+// beyond the owner's are hinted, since only the owner can check conditions and a hint is a single copy.
+// An owner that still answers pings was only slow, and may have applied the put already.
+func (c *Client) shouldHint(ctx context.Context, owner Address, request PutRequest, err error) bool {
+	if !unreachable(ctx, err) || request.Condition != (Condition{}) || request.Consistency.W > 1 {
+		return false
+	}
+	var success bool
+	return c.call(ctx, owner, "NodeActor.Ping", None{}, &success) != nil
+}
+
+// Hand a put for an unreachable owner to the first seed that accepts it
+func (c *Client) hint(ctx context.Context, owner Address, request PutRequest) error {
+	var err error
+	for _, seed := range c.seeds {
+		if seed == owner {
+			continue
+		}
+		if err = c.call(ctx, seed, "NodeActor.Hint", Hint{Owner: owner, Request: request}, &None{}); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Take a hint to deliver later, recording what the put replaces if the writer left that to the owner.
+// Such a put replaces every version this node knows of, including earlier hinted puts of the key.
+func (n *Node) holdHint(hint Hint) error {
+	if hint.Request.Seen == nil {
+		known, err := n.Data.Get(hint.Request.Key)
+		if err != nil {
+			return err
+		}
+		kept := []Hint{}
+		for _, held := range n.hints {
+			if held.Request.Key != hint.Request.Key {
+				kept = append(kept, held)
+			}
+		}
+		n.hints = kept
+		seen := known.Context()
+		// A clock with no entries is decoded as nil, which would replace every version again, so list the owner as seen up to nothing
+		if _, listed := seen[hint.Owner]; !listed {
+			seen[hint.Owner] = 0
+		}
+		hint.Request.Seen = seen
+	}
+	n.hints = append(n.hints, hint)
+	return n.saveHints()
+}
+
+// Save the hints to the data directory, if the node has one
+func (n *Node) saveHints() error {
+	if n.config.DataDir == "" {
+		return nil
+	}
+	data, err := json.Marshal(n.hints)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(n.config.DataDir, hintsFile)
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("saving hints: %v", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("saving hints: %v", err)
+	}
+	return nil
+}
+
+// Read the hints saved in a data directory, if there are any
+func loadHints(dir string) ([]Hint, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, hintsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading saved hints: %v", err)
+	}
+	var hints []Hint
+	if err := json.Unmarshal(data, &hints); err != nil {
+		return nil, fmt.Errorf("reading saved hints: %v", err)
+	}
+	return hints, nil
+}
+
+// Try to deliver every hint held by the node, keeping the ones that fail for the next round
+func (n *Node) deliverHints(ctx context.Context) error {
+	var hints []Hint
+	n.actor.run(func(n *Node) {
+		hints, n.hints = n.hints, nil
+	})
+	if len(hints) == 0 {
+		return nil
+	}
+	remaining := []Hint{}
+	for _, hint := range hints {
+		if hint.Request.TTL > 0 && time.Since(hint.Received) >= hint.Request.TTL {
+			// The value would already have expired on the owner
+			continue
+		}
+		if err := n.deliverHint(ctx, &hint); err == ErrValueTooLarge || err == errHintExpired {
+			// The owner will never accept it, or it would already have expired there
+			n.logger.Printf("dropping hinted put of %s for %s: %v", string(hint.Request.Key), hint.Owner, err)
+			continue
+		} else if err != nil {
+			remaining = append(remaining, hint)
+			continue
+		}
+		n.logger.Printf("delivered hinted put of %s to %s", string(hint.Request.Key), hint.Owner)
+	}
+	var err error
+	n.actor.run(func(n *Node) {
+		n.hints = append(remaining, n.hints...)
+		err = n.saveHints()
+	})
+	return err
+}
+
+// Deliver a hint to its owner once it answers a ping, or to the new owner of the key if that has changed
+func (n *Node) deliverHint(ctx context.Context, hint *Hint) error {
+	var success bool
+	if err := n.client.call(ctx, hint.Owner, "NodeActor.Ping", None{}, &success); err != nil {
+		owner, lookupErr := n.client.Lookup(ctx, hint.Request.Key)
+		if lookupErr != nil || owner == hint.Owner {
+			return err
+		}
+		n.logger.Printf("hinted put of %s now owned by %s instead of %s", string(hint.Request.Key), owner, hint.Owner)
+		hint.Owner = owner
+	}
+	request := hint.Request
+	// Finding the owner takes time, so the TTL left is only known now
+	if request.TTL > 0 {
+		if request.TTL -= time.Since(hint.Received); request.TTL <= 0 {
+			return errHintExpired
+		}
+	}
+	return n.client.coordinate(ctx, hint.Owner, "NodeActor.Put", request, &None{})
+}
@@ -0,0 +1,108 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// A ring where every node saves to its own data directory, and a client whose calls give up quickly
+func hintRing(t *testing.T, network *MemoryNetwork) ([]*Node, *Client) {
+	dir := t.TempDir()
+	started := 0
+	nodes := testRing(t, network, 3, func(cfg *Config) {
+		cfg.DataDir = filepath.Join(dir, fmt.Sprint(started))
+		started++
+	})
+	seeds := []Address{}
+	for _, n := range nodes {
+		seeds = append(seeds, n.Address)
+	}
+	client, err := NewClient(testConfig(network, "client"), seeds...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return nodes, client
+}
+
+// The hints a node holds, read through its actor
+func heldHints(n *Node) []Hint {
+	var hints []Hint
+	n.actor.run(func(n *Node) {
+		hints = append(hints, n.hints...)
+	})
+	return hints
+}
+
+// The node holding hints
+func hintHolder(t *testing.T, nodes []*Node) *Node {
+	t.Helper()
+	for _, n := range nodes {
+		if len(heldHints(n)) > 0 {
+			return n
+		}
+	}
+	t.Fatal("no node holds a hint")
+	return nil
+}
+
+func TestHintedPut(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes, client := hintRing(t, network)
+	ctx := context.Background()
+	owner := ownerOf(nodes, "key")[0]
+
+	network.Partition("client", owner.Address)
+	if err := client.Put(ctx, "key", "hinted"); err != nil {
+		t.Fatalf("put with the owner cut off: %v", err)
+	}
+	holder := hintHolder(t, nodes)
+	saved, err := loadHints(holder.config.DataDir)
+	if err != nil || len(saved) != 1 || string(saved[0].Request.Value) != "hinted" {
+		t.Fatalf("saved hints %v, %v", saved, err)
+	}
+
+	network.Heal()
+	holder.deliverHints(ctx)
+	if hints := heldHints(holder); len(hints) != 0 {
+		t.Errorf("still holding %d hints after delivering them", len(hints))
+	}
+	if saved, err := loadHints(holder.config.DataDir); err != nil || len(saved) != 0 {
+		t.Errorf("saved hints after delivery %v, %v", saved, err)
+	}
+	if value, err := client.Get(ctx, "key"); err != nil || value != "hinted" {
+		t.Errorf("after delivery: got %q, %v", value, err)
+	}
+}
+
+func TestHintKeepsLaterWrites(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes, client := hintRing(t, network)
+	ctx := context.Background()
+	owner := ownerOf(nodes, "key")[0]
+
+	network.Partition("client", owner.Address)
+	if err := client.Put(ctx, "key", "hinted"); err != nil {
+		t.Fatal(err)
+	}
+	holder := hintHolder(t, nodes)
+	// Another writer reaches the owner before the hint is delivered
+	if err := owner.client.Put(ctx, "key", "later"); err != nil {
+		t.Fatal(err)
+	}
+	network.Heal()
+	holder.deliverHints(ctx)
+
+	siblings, err := client.GetVersions(ctx, "key", Consistency{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := siblings.Values()
+	sort.Strings(values)
+	if len(values) != 2 || values[0] != "hinted" || values[1] != "later" {
+		t.Errorf("after delivery the key holds %q, expected both writes", values)
+	}
+}
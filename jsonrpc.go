@@ -0,0 +1,77 @@
+package chord
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+)
+
+// Serve the NodeActor methods with the JSON-RPC 1.0 codec on a separate port so clients not written in Go can use the node.
+// Requests look like {"method": "NodeActor.Get", "params": [{"Key": "key"}], "id": 1}, with values base64 encoded.
+func (n *Node) startJSONRPC(actor NodeActor) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(n.config.BindAddress, fmt.Sprint(n.config.JSONRPCPort)))
+	if err != nil {
+		return fmt.Errorf("JSON-RPC listen error: %v", err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("NodeActor", jsonActor{actor}); err != nil {
+		listener.Close()
+		return fmt.Errorf("registering actor: %v", err)
+	}
+	n.jsonListener = newTrackingListener(listener)
+	go func() {
+		for {
+			conn, err := n.jsonListener.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+	n.logger.Printf("Serving JSON-RPC on %s", listener.Addr())
+	return nil
+}
+
+// The NodeActor methods as served over JSON-RPC. Its callers are clients of the ring rather than nodes or the Go client
+// storing chunks, so writes to reserved keys are refused as the Go client refuses them.
+type jsonActor struct {
+	NodeActor
+}
+
+func (a jsonActor) Put(request PutRequest, reply *None) error {
+	if err := checkKey(request.Key); err != nil {
+		return err
+	}
+	return a.NodeActor.Put(request, reply)
+}
+
+func (a jsonActor) Delete(request DeleteRequest, siblings *Siblings) error {
+	if err := checkKey(request.Key); err != nil {
+		return err
+	}
+	return a.NodeActor.Delete(request, siblings)
+}
+
+func (a jsonActor) MultiPut(request MultiPutRequest, reply *None) error {
+	for key := range request.Items {
+		if err := checkKey(key); err != nil {
+			return err
+		}
+	}
+	return a.NodeActor.MultiPut(request, reply)
+}
+
+func (a jsonActor) MultiDelete(request MultiKeyRequest, items *map[Key]Siblings) error {
+	if err := checkKeys(request.Keys); err != nil {
+		return err
+	}
+	return a.NodeActor.MultiDelete(request, items)
+}
+
+func (a jsonActor) Hint(hint Hint, reply *None) error {
+	if err := checkKey(hint.Request.Key); err != nil {
+		return err
+	}
+	return a.NodeActor.Hint(hint, reply)
+}
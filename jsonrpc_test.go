@@ -0,0 +1,72 @@
+package chord
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestJSONRPC(t *testing.T) {
+	// Find a free port for the JSON-RPC listener
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	network := NewMemoryNetwork()
+	testRing(t, network, 1, func(cfg *Config) {
+		cfg.BindAddress = "127.0.0.1"
+		cfg.JSONRPCPort = port
+	})
+	conn, err := net.Dial("tcp", fmt.Sprint("127.0.0.1:", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	replies := bufio.NewScanner(conn)
+	exchange := func(request string) map[string]interface{} {
+		t.Helper()
+		fmt.Fprintln(conn, request)
+		if !replies.Scan() {
+			t.Fatalf("no reply to %s: %v", request, replies.Err())
+		}
+		var reply map[string]interface{}
+		if err := json.Unmarshal(replies.Bytes(), &reply); err != nil {
+			t.Fatalf("reply to %s: %v", request, err)
+		}
+		return reply
+	}
+	send := func(request string) map[string]interface{} {
+		t.Helper()
+		reply := exchange(request)
+		if reply["error"] != nil {
+			t.Fatalf("reply to %s: %v", request, reply["error"])
+		}
+		return reply
+	}
+
+	send(`{"method": "NodeActor.Put", "params": [{"Key": "key", "Value": "dmFsdWU="}], "id": 1}`)
+	reply := send(`{"method": "NodeActor.Get", "params": [{"Key": "key"}], "id": 2}`)
+	versions, ok := reply["result"].([]interface{})
+	if !ok || len(versions) != 1 || versions[0].(map[string]interface{})["Value"] != "dmFsdWU=" {
+		t.Errorf("Get replied %v", reply["result"])
+	}
+
+	// Callers over JSON-RPC cannot write the keys the ring keeps for itself, but can write the keys of buckets
+	for id, request := range []string{
+		`"NodeActor.Put", "params": [{"Key": "\u0000chunk/abc/key", "Value": "dmFsdWU="}]`,
+		`"NodeActor.MultiPut", "params": [{"Items": {"\u0000chunk/abc/key": "dmFsdWU="}}]`,
+		`"NodeActor.Delete", "params": [{"Key": "\u0000chunk/abc/key"}]`,
+		`"NodeActor.MultiDelete", "params": [{"Keys": ["\u0000chunk/abc/key"]}]`,
+		`"NodeActor.Hint", "params": [{"Owner": "node0", "Request": {"Key": "\u0000chunk/abc/key"}}]`,
+	} {
+		if reply := exchange(fmt.Sprintf(`{"method": %s, "id": %d}`, request, id+3)); reply["error"] != ErrReservedKey.Error() {
+			t.Errorf("%s replied %v, expected the key to be refused", request, reply)
+		}
+	}
+	send(`{"method": "NodeActor.Put", "params": [{"Key": "\u0000bucket/users\u0000key", "Value": "dmFsdWU="}], "id": 8}`)
+}
@@ -1,28 +1,39 @@
-package main
+package chord
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"math/big"
+	"os"
 	"sort"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Local unexported node functions
 
 // Find returns the address of the node responsible (successor) for the given id.
 // Node agnostic, just acts on a ring
-func find(id *big.Int, start Address) (Address, error) {
+// The whole lookup gives up when the context is done.
+func (c *Client) find(ctx context.Context, id *big.Int, start Address) (Address, error) {
 	result := AddressResult{
 		Found:   false,
 		Address: start,
 	}
 	i := 0
 	for !result.Found && i < maxRequests {
-		if err := call(result.Address, "NodeActor.FindSuccessor", id, &result); err != nil {
+		// A call that timed out can still fill in its reply later, so each hop gets its own
+		var next AddressResult
+		if err := c.call(ctx, result.Address, "NodeActor.FindSuccessor", id, &next); err != nil {
+			if ctx.Err() != nil {
+				return result.Address, contextError(ctx, fmt.Sprintf("lookup after %d hops", i))
+			}
 			return result.Address, fmt.Errorf("find successor: %v", err)
 		}
+		result = next
 		i++
 	}
 	if result.Found {
@@ -32,7 +43,7 @@ func find(id *big.Int, start Address) (Address, error) {
 }
 
 // Search local fingers for highest predecessor of id
-func (n Node) closestPrecedingNode(id *big.Int) Address {
+func (n *Node) closestPrecedingNode(id *big.Int) Address {
 	for i := numFingerEntries - 1; i > 0; i-- {
 		if n.Fingers[i] == "" {
 			continue
@@ -46,51 +57,55 @@ func (n Node) closestPrecedingNode(id *big.Int) Address {
 	return n.Successors[0]
 }
 
-// Create local node instance
-func createNode() *Node {
-	return &Node{
-		Address: Address(localHost + ":" + fmt.Sprint(localPort)),
-		Hash:    Address(localHost + ":" + fmt.Sprint(localPort)).hashed(),
-		Data:    make(map[Key]string),
+// Create local node instance listening on its transport
+func createNode(cfg Config) (*Node, error) {
+	cfg = cfg.withDefaults()
+	if cfg.Transport == nil {
+		cfg.Transport = NewRPCTransport(cfg)
+	}
+	if cfg.DataDir != "" {
+		if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+			return nil, fmt.Errorf("creating data directory: %v", err)
+		}
 	}
-}
-
-// Create a new chord ring
-func createRing() (*Node, error) {
-	n := createNode()
-	if err := n.startNode(); err != nil {
-		return n, fmt.Errorf("starting node RPC server: %v", err)
-	}
-	log.Println("created ring successfully")
-	// Set successor to itself
-	n.Successors = append(n.Successors, n.Address)
-	// Start background tasks
-	n.startBackgroundMaintenance()
-	return n, nil
-}
-
-// Join an existing chord ring
-func joinRing(joinAddress Address) (*Node, error) {
-	n := createNode()
-	// Call find starting at supplied address, searching for local address
-	successor, err := find(n.Address.hashed(), joinAddress)
+	if cfg.Storage == nil && cfg.DataDir != "" {
+		storage, err := NewFileStorage(cfg.DataDir, FileStorageOptions{})
+		if err != nil {
+			return nil, err
+		}
+		cfg.Storage = storage
+	}
+	if cfg.Storage == nil {
+		cfg.Storage = NewMemoryStorage()
+	}
+	var hints []Hint
+	if cfg.DataDir != "" {
+		var err error
+		if hints, err = loadHints(cfg.DataDir); err != nil {
+			cfg.Storage.Close()
+			return nil, err
+		}
+	}
+	address, err := cfg.Transport.Listen()
 	if err != nil {
-		return nil, fmt.Errorf("finding place on ring: %v", err)
-	}
-	// Now start server
-	if err := n.startNode(); err != nil {
-		return n, fmt.Errorf("starting node RPC server: %v", err)
-	}
-	log.Printf("joining ring @ %s\n", successor)
-	// Set successor
-	n.Successors = append(n.Successors, successor)
-	// Start background tasks
-	n.startBackgroundMaintenance()
-	// Ask for successor for any data that should be ours
-	if err := call(successor, "NodeActor.GetAll", n.Address, &n.Data); err != nil {
-		log.Fatalf("transferring data from successor: %v", err)
-	}
-	log.Println("Successfully transferred data to successor")
+		// The storage belongs to the node, so it is closed along with it
+		cfg.Storage.Close()
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Node{
+		Address:   address,
+		Hash:      address.hashed(),
+		config:    cfg,
+		logger:    cfg.Logger,
+		transport: cfg.Transport,
+		client:    newClient(cfg, address),
+		ctx:       ctx,
+		cancel:    cancel,
+		hints:     hints,
+		watches:   make(watchHub),
+	}
+	n.Data = &watchedStorage{cfg.Storage, n}
 	return n, nil
 }
 
@@ -102,26 +117,13 @@ func between(start *big.Int, elt *big.Int, end *big.Int, inclusive bool) bool {
 	return start.Cmp(elt) < 0 || elt.Cmp(end) < 0 || (inclusive && elt.Cmp(end) == 0)
 }
 
-// Stringer interface for Node dump
-func (n Node) String() string {
-	var w strings.Builder
-	w.WriteString("DUMP: Node info\n\n")
-	w.WriteString(fmt.Sprintf("Predecessor: %s\n\n", n.Predecessor))
-	w.WriteString(fmt.Sprintf("Address: %s\n\n", n.Address))
-	for i, successor := range n.Successors {
-		w.WriteString(fmt.Sprintf("Sucessor[%d]: %s\n", i, successor))
-	}
-
-	// Print only unique finger table entries
+// The last finger table entry for each distinct address, ordered by entry
+func (n *Node) uniqueFingers() []fingerEntry {
 	unique := make(map[Address]int)
 	for i, address := range n.Fingers {
 		unique[address] = i
 	}
 
-	type fingerEntry struct {
-		entry   int
-		address Address
-	}
 	orderedUnique := []fingerEntry{}
 	for address, entry := range unique {
 		if address != "" {
@@ -129,34 +131,76 @@ func (n Node) String() string {
 		}
 	}
 	sort.Slice(orderedUnique, func(i, j int) bool {
-		return orderedUnique[i].entry < orderedUnique[j].entry
+		return orderedUnique[i].Entry < orderedUnique[j].Entry
 	})
+	return orderedUnique
+}
+
+// Stringer interface for Node dump
+func (n *Node) String() string {
+	var w strings.Builder
+	w.WriteString("DUMP: Node info\n\n")
+	w.WriteString(fmt.Sprintf("Predecessor: %s\n\n", n.Predecessor))
+	w.WriteString(fmt.Sprintf("Address: %s\n\n", n.Address))
+	for i, successor := range n.Successors {
+		w.WriteString(fmt.Sprintf("Sucessor[%d]: %s\n", i, successor))
+	}
+
+	// Print only unique finger table entries
 	w.WriteString("\nFinger table:\n")
-	for _, finger := range orderedUnique {
-		w.WriteString(fmt.Sprintf("   %-5s: %s\n", fmt.Sprintf("[%d]", finger.entry), finger.address))
+	for _, finger := range n.uniqueFingers() {
+		w.WriteString(fmt.Sprintf("   %-5s: %s\n", fmt.Sprintf("[%d]", finger.Entry), finger.Address))
 	}
 
-	if len(n.Data) > 0 {
+	data, err := n.Data.Snapshot()
+	if err != nil {
+		w.WriteString(fmt.Sprintf("\nData items unavailable: %v\n", err))
+	} else if len(data) > 0 {
 		w.WriteString("\nData items:\n")
 		// Order keys in map by hash
-		ordered := []KeyValue{}
-		for key, value := range n.Data {
-			ordered = append(ordered, KeyValue{key, value})
+		ordered := []Key{}
+		for key := range data {
+			ordered = append(ordered, key)
 		}
 		sort.Slice(ordered, func(i, j int) bool {
-			return ordered[i].Key.hashed().Cmp(ordered[j].Key.hashed()) < 0
+			return ordered[i].hashed().Cmp(ordered[j].hashed()) < 0
 		})
-		for _, kv := range ordered {
-			w.WriteString(fmt.Sprintf("   %s\n", kv))
+		for _, key := range ordered {
+			for _, item := range data[key] {
+				line := fmt.Sprintf("   %s (%s)", KeyValue{key, item.Value}, item.Version)
+				if item.deleted() {
+					line = fmt.Sprintf("   %-20s (%s) (deleted)", key, item.Version)
+				}
+				if !item.Expires.IsZero() {
+					line += fmt.Sprintf(" (expires in %v)", time.Until(item.Expires).Round(time.Second))
+				}
+				if !n.owns(key) {
+					line += " (replica)"
+				}
+				if len(data[key]) > 1 {
+					line += " (sibling)"
+				}
+				w.WriteString(line + "\n")
+			}
 		}
 	} else {
 		w.WriteString("\nNo data items\n")
 	}
 
+	if len(n.hints) > 0 {
+		w.WriteString("\nHinted puts:\n")
+		for _, hint := range n.hints {
+			w.WriteString(fmt.Sprintf("   %s for %s\n", hint.Request.KeyValue, string(hint.Owner)))
+		}
+	}
+
 	return w.String()
 }
 
-// Print a key value pair
+// Print a key value pair, with the size in place of values that are not printable text
 func (kv KeyValue) String() string {
+	if !utf8.Valid(kv.Value) || strings.IndexFunc(string(kv.Value), func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
+		return fmt.Sprintf("%-20s => <%d bytes>", kv.Key, len(kv.Value))
+	}
 	return fmt.Sprintf("%-20s => %s", kv.Key, kv.Value)
 }
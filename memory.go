@@ -0,0 +1,114 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+type (
+	// MemoryNetwork connects in-memory transports so whole rings can run inside one process without opening sockets.
+	// Faults such as latency, message loss, partitions and crashes can be injected to simulate a real network.
+	MemoryNetwork struct {
+		mu      sync.Mutex
+		servers map[Address]*rpc.Server // Claimed addresses, nil until the node starts serving
+
+		links         map[link]LinkFaults // Faults injected into specific links
+		defaultFaults LinkFaults          // Faults injected into every other link
+		crashed       map[Address]bool    // Addresses that drop all messages
+		rng           *rand.Rand          // Decides which messages are lost
+	}
+
+	// A transport attached to a MemoryNetwork at a fixed address
+	memoryTransport struct {
+		network   *MemoryNetwork
+		address   Address
+		listening bool // Whether the address was claimed by this transport
+	}
+)
+
+// NewMemoryNetwork creates an empty in-memory network
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{
+		servers: make(map[Address]*rpc.Server),
+		links:   make(map[link]LinkFaults),
+		crashed: make(map[Address]bool),
+		rng:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Transport creates a transport on the network for a node at the given address.
+// Clients that never listen can use any address.
+func (m *MemoryNetwork) Transport(address Address) Transport {
+	return &memoryTransport{
+		network: m,
+		address: address,
+	}
+}
+
+// Connect to the server at an address over an in-memory pipe
+func (m *MemoryNetwork) dial(address Address) (*rpc.Client, error) {
+	m.mu.Lock()
+	server := m.servers[address]
+	m.mu.Unlock()
+	if server == nil {
+		return nil, fmt.Errorf("dialing %s: connection refused", string(address))
+	}
+	clientConn, serverConn := net.Pipe()
+	go server.ServeConn(serverConn)
+	return rpc.NewClient(clientConn), nil
+}
+
+func (t *memoryTransport) Listen() (Address, error) {
+	t.network.mu.Lock()
+	defer t.network.mu.Unlock()
+	if _, exists := t.network.servers[t.address]; exists {
+		return "", fmt.Errorf("listen error: address %s already in use", string(t.address))
+	}
+	t.network.servers[t.address] = nil
+	t.listening = true
+	return t.address, nil
+}
+
+func (t *memoryTransport) Serve(actor NodeActor) error {
+	server := rpc.NewServer()
+	if err := server.Register(actor); err != nil {
+		return fmt.Errorf("registering actor: %v", err)
+	}
+	t.network.mu.Lock()
+	t.network.servers[t.address] = server
+	t.network.mu.Unlock()
+	return nil
+}
+
+func (t *memoryTransport) Call(ctx context.Context, address Address, method string, request interface{}, reply interface{}) error {
+	// Send the request
+	if err := t.network.deliver(ctx, t.address, address, method); err != nil {
+		return err
+	}
+	client, err := t.network.dial(address)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	err = invoke(ctx, client, method, request, reply)
+	// Send the reply back
+	if err := t.network.deliver(ctx, address, t.address, method); err != nil {
+		return err
+	}
+	return err
+}
+
+func (t *memoryTransport) Close() error {
+	t.network.mu.Lock()
+	defer t.network.mu.Unlock()
+	if t.listening {
+		delete(t.network.servers, t.address)
+		t.listening = false
+	}
+	return nil
+}
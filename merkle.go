@@ -0,0 +1,207 @@
+package chord
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Anti-entropy: every node periodically compares the items in its own range (predecessor, self]
+// with each of its replicas using a Merkle tree. Hashes are compared from the root down, one level
+// per call, and only the leaves whose hashes differ have their items exchanged and merged both ways.
+// Tombstones are exchanged like values, so a replica that missed a delete gets it here.
+
+const (
+	merkleDepth = 6 // Levels below the root, so the tree has 2^merkleDepth leaves
+)
+
+type (
+	// The hashes of a Merkle tree by level, from the root at level 0 to the leaves at level merkleDepth
+	merkleTree [][][]byte
+
+	// MerkleRequest asks for parts of a Merkle tree over the keys in the range (Start, End] of the ring
+	MerkleRequest struct {
+		Start   *big.Int
+		End     *big.Int
+		Level   int   // The level of the tree the indexes refer to
+		Indexes []int // The tree nodes wanted at that level
+	}
+)
+
+// The leaf of a Merkle tree over (start, end] a key falls in. The range is split into equal parts.
+func merkleLeaf(key Key, start, end *big.Int) int {
+	// Distances are measured clockwise from start, so ranges that wrap around zero work too
+	offset := new(big.Int).Sub(key.hashed(), start)
+	offset.Mod(offset, hashMod)
+	size := new(big.Int).Sub(end, start)
+	size.Mod(size, hashMod)
+	if size.Sign() == 0 {
+		// The whole ring
+		size.Set(hashMod)
+	}
+	offset.Sub(offset, big.NewInt(1))
+	offset.Lsh(offset, merkleDepth)
+	return int(offset.Div(offset, size).Int64())
+}
+
+// The items of the node in the range (start, end], grouped by leaf of the Merkle tree over the range
+func (n *Node) merkleLeaves(start, end *big.Int) ([]map[Key]Siblings, error) {
+	items, err := n.Data.Range(start, end)
+	if err != nil {
+		return nil, err
+	}
+	leaves := make([]map[Key]Siblings, 1<<merkleDepth)
+	for i := range leaves {
+		leaves[i] = make(map[Key]Siblings)
+	}
+	for key, siblings := range items {
+		leaves[merkleLeaf(key, start, end)][key] = siblings
+	}
+	return leaves, nil
+}
+
+// Build a Merkle tree over the items of the node in the range (start, end]
+func (n *Node) merkleTree(start, end *big.Int) (merkleTree, error) {
+	leaves, err := n.merkleLeaves(start, end)
+	if err != nil {
+		return nil, err
+	}
+	tree := make(merkleTree, merkleDepth+1)
+	for _, items := range leaves {
+		tree[merkleDepth] = append(tree[merkleDepth], hashLeaf(items))
+	}
+	for level := merkleDepth - 1; level >= 0; level-- {
+		below := tree[level+1]
+		for i := 0; i < len(below); i += 2 {
+			hasher := sha1.New()
+			hasher.Write(below[i])
+			hasher.Write(below[i+1])
+			tree[level] = append(tree[level], hasher.Sum(nil))
+		}
+	}
+	return tree, nil
+}
+
+// Hash the items in a leaf. Each item is hashed by its version and a digest of its contents and flags,
+// so copies of a write that differ, or a value and its tombstone, hash differently.
+func hashLeaf(items map[Key]Siblings) []byte {
+	keys := []Key{}
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	hasher := sha1.New()
+	for _, key := range keys {
+		fmt.Fprintf(hasher, "%q:", string(key))
+		for _, item := range items[key] {
+			fmt.Fprintf(hasher, "%s/%x/%s/%s/%t,", item.Version, sha1.Sum(item.Value),
+				item.Expires.UTC().Format(time.RFC3339Nano), item.Deleted.UTC().Format(time.RFC3339Nano), item.Manifest)
+		}
+		hasher.Write([]byte{'\n'})
+	}
+	return hasher.Sum(nil)
+}
+
+// Compare the items in this node's range with every replica and exchange the ones that differ
+func (n *Node) antiEntropy(ctx context.Context) error {
+	var start, end *big.Int
+	var targets []Address
+	n.actor.run(func(n *Node) {
+		if n.Predecessor != "" {
+			start, end = n.Predecessor.hashed(), n.Hash
+			targets = n.replicaTargets(n.config.Replicas - 1)
+		}
+	})
+	for _, target := range targets {
+		if err := n.syncRange(ctx, target, start, end); err != nil {
+			n.logger.Printf("anti-entropy with %s: %v", target, err)
+		}
+	}
+	return nil
+}
+
+// Walk down the Merkle trees of this node and another over the range (start, end], then exchange the items of differing leaves
+func (n *Node) syncRange(ctx context.Context, address Address, start, end *big.Int) error {
+	var tree merkleTree
+	var err error
+	n.actor.run(func(n *Node) {
+		tree, err = n.merkleTree(start, end)
+	})
+	if err != nil {
+		return err
+	}
+
+	differing := []int{0}
+	for level := 0; level <= merkleDepth && len(differing) > 0; level++ {
+		var hashes [][]byte
+		request := MerkleRequest{start, end, level, differing}
+		if err := n.client.call(ctx, address, "NodeActor.MerkleHashes", request, &hashes); err != nil {
+			return err
+		}
+		if len(hashes) != len(differing) {
+			return fmt.Errorf("asked for %d hashes, got %d", len(differing), len(hashes))
+		}
+		mismatched := []int{}
+		for i, index := range differing {
+			if !bytes.Equal(tree[level][index], hashes[i]) {
+				mismatched = append(mismatched, index)
+			}
+		}
+		if level == merkleDepth {
+			differing = mismatched
+			break
+		}
+		// Descend into the children of the nodes that differ
+		differing = []int{}
+		for _, index := range mismatched {
+			differing = append(differing, 2*index, 2*index+1)
+		}
+	}
+	if len(differing) == 0 {
+		return nil
+	}
+
+	// Merge the items of the differing leaves both ways
+	theirs := make(map[Key]Siblings)
+	request := MerkleRequest{start, end, merkleDepth, differing}
+	if err := n.client.call(ctx, address, "NodeActor.MerkleItems", request, &theirs); err != nil {
+		return err
+	}
+	var ours map[Key]Siblings
+	n.actor.run(func(n *Node) {
+		if ours, err = n.merkleItems(request); err != nil {
+			return
+		}
+		for key, siblings := range theirs {
+			if err = n.merge(key, siblings...); err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	n.logger.Printf("anti-entropy: %d of %d leaves differ with %s, exchanging %d and %d items", len(differing), 1<<merkleDepth, address, len(ours), len(theirs))
+	return n.client.call(ctx, address, "NodeActor.PutAll", ours, &None{})
+}
+
+// The items of the node in the requested leaves of a Merkle tree
+func (n *Node) merkleItems(request MerkleRequest) (map[Key]Siblings, error) {
+	leaves, err := n.merkleLeaves(request.Start, request.End)
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[Key]Siblings)
+	for _, index := range request.Indexes {
+		if index >= 0 && index < len(leaves) {
+			for key, siblings := range leaves[index] {
+				items[key] = siblings
+			}
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,143 @@
+package chord
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestMerkleLeaf(t *testing.T) {
+	whole := big.NewInt(0)
+	for i := 0; i < 200; i++ {
+		key := Key(fmt.Sprint("key", i))
+		if leaf := merkleLeaf(key, whole, whole); leaf < 0 || leaf >= 1<<merkleDepth {
+			t.Errorf("%s is in leaf %d of the whole ring", string(key), leaf)
+		}
+	}
+	// A range that wraps around zero, with the key half way along it
+	half := new(big.Int).Rsh(hashMod, 1)
+	start := new(big.Int).Add(Key("key").hashed(), half)
+	start.Mod(start, hashMod)
+	end := new(big.Int).Sub(start, big.NewInt(1))
+	end.Mod(end, hashMod)
+	if leaf := merkleLeaf("key", start, end); leaf != 1<<merkleDepth/2-1 {
+		t.Errorf("key half way along a wrapping range is in leaf %d", leaf)
+	}
+}
+
+func TestHashLeafCoversContents(t *testing.T) {
+	item := Item{Value: []byte("a"), Version: Version{"n1", 1, VectorClock{}}}
+	hash := hashLeaf(map[Key]Siblings{"key": {item}})
+	changed := item
+	changed.Value = []byte("b")
+	if bytes.Equal(hash, hashLeaf(map[Key]Siblings{"key": {changed}})) {
+		t.Error("items with the same version and different values hash the same")
+	}
+	tombstone := Item{Version: item.Version, Deleted: time.Now()}
+	if bytes.Equal(hash, hashLeaf(map[Key]Siblings{"key": {tombstone}})) {
+		t.Error("an item and its tombstone hash the same")
+	}
+}
+
+func TestAntiEntropyRestoresItems(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 4, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		if err := client.PutWith(ctx, Key(fmt.Sprint("key", i)), fmt.Sprint(i), Consistency{W: 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Lose every item of one node
+	wiped := nodes[1]
+	wiped.actor.run(func(n *Node) {
+		n.Data = &watchedStorage{NewMemoryStorage(), n}
+	})
+	for _, n := range nodes {
+		n.antiEntropy(ctx)
+	}
+	for i := 0; i < 100; i++ {
+		key := Key(fmt.Sprint("key", i))
+		for _, n := range ownerOf(nodes, key)[:3] {
+			if !holds(n, key, fmt.Sprint(i)) {
+				t.Errorf("%s holds %v for %s", n.Address, stored(n, key), string(key))
+			}
+		}
+	}
+}
+
+func TestAntiEntropyKeepsDeletes(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	replicas := ownerOf(nodes, "gone")
+	if err := client.PutWith(ctx, "gone", "v", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+	network.Partition(replicas[0].Address, replicas[2].Address)
+	if _, err := client.Delete(ctx, "gone"); err != nil {
+		t.Fatal(err)
+	}
+	network.Heal()
+
+	replicas[0].antiEntropy(ctx)
+	if siblings := stored(replicas[2], "gone"); len(siblings) != 1 || !siblings[0].deleted() {
+		t.Errorf("replica that missed the delete holds %v", siblings)
+	}
+	if value, err := client.GetWith(ctx, "gone", Consistency{R: 3}); err != ErrNoSuchKey {
+		t.Errorf("get after anti-entropy: got %q, %v", value, err)
+	}
+}
+
+func TestReadRepairKeepsDeletes(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	replicas := ownerOf(nodes, "gone")
+	if err := client.PutWith(ctx, "gone", "v", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+	network.Partition(replicas[0].Address, replicas[2].Address)
+	if _, err := client.Delete(ctx, "gone"); err != nil {
+		t.Fatal(err)
+	}
+	network.Heal()
+
+	if value, err := client.GetWith(ctx, "gone", Consistency{R: 3}); err != ErrNoSuchKey {
+		t.Errorf("quorum read of a deleted key: got %q, %v", value, err)
+	}
+	eventually(t, "the tombstone to be repaired", func() bool {
+		siblings := stored(replicas[2], "gone")
+		return len(siblings) == 1 && siblings[0].deleted()
+	})
+}
+
+func TestTombstonesAreCollected(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 1, func(cfg *Config) {
+		cfg.TombstoneTTL = 200 * time.Millisecond
+	})
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.Put(ctx, "key", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	nodes[0].expire(ctx)
+	if siblings := stored(nodes[0], "key"); len(siblings) != 1 {
+		t.Fatalf("new tombstone: holds %v", siblings)
+	}
+	time.Sleep(200 * time.Millisecond)
+	nodes[0].expire(ctx)
+	if siblings := stored(nodes[0], "key"); siblings != nil {
+		t.Errorf("old tombstone: holds %v", siblings)
+	}
+}
@@ -0,0 +1,229 @@
+package chord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// A node with a data directory saves its address and successor list there alongside its items.
+// After a crash Restart brings it back at the same address with the same items, rejoins the ring
+// through the saved successors, and hands the items it no longer owns to their owners.
+
+const stateFile = "node.json"
+
+// What a node saves about its place on the ring
+type nodeState struct {
+	Address    Address   `json:"address"`
+	Successors []Address `json:"successors"`
+}
+
+// Restart brings back a node that ran with cfg.DataDir before, at the same address and with the items it held.
+// It rejoins the ring through the first of its saved successors that answers, or recreates the ring if it was alone.
+// Reloaded items that now belong to other nodes are handed to them in the background.
+func Restart(cfg Config) (*Node, error) {
+	if cfg.DataDir == "" {
+		return nil, errors.New("restarting needs the data directory the node used before")
+	}
+	state, err := loadState(cfg.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Transport == nil {
+		// Listen where the node listened before
+		host, port, err := net.SplitHostPort(string(state.Address))
+		if err != nil {
+			return nil, fmt.Errorf("saved address %s: %v", string(state.Address), err)
+		}
+		if cfg.Port, err = strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("saved address %s: %v", string(state.Address), err)
+		}
+		cfg.AdvertiseAddress = host
+	}
+	n, err := createNode(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if n.Address != state.Address {
+		n.stopNode()
+		return nil, fmt.Errorf("restarted at %s instead of the saved address %s", string(n.Address), string(state.Address))
+	}
+
+	others := []Address{}
+	for _, successor := range state.Successors {
+		if successor != n.Address {
+			others = append(others, successor)
+		}
+	}
+	if len(others) == 0 {
+		n.logger.Println("restarting as the only member of the ring")
+		if err := n.create(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	for _, successor := range others {
+		var success bool
+		if err = n.client.call(n.ctx, successor, "NodeActor.Ping", None{}, &success); err == nil {
+			n.logger.Printf("rejoining ring through %s", successor)
+			if err := n.join(successor); err != nil {
+				return nil, err
+			}
+			go n.reconcile(n.ctx)
+			return n, nil
+		}
+	}
+	n.stopNode()
+	return nil, fmt.Errorf("none of the saved successors answered: %v", err)
+}
+
+// Read the saved state of a node
+func loadState(dir string) (nodeState, error) {
+	var state nodeState
+	data, err := ioutil.ReadFile(filepath.Join(dir, stateFile))
+	if err != nil {
+		return state, fmt.Errorf("reading saved node: %v", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("reading saved node: %v", err)
+	}
+	return state, nil
+}
+
+// Save the node's address and successors to its data directory if they changed since the last save
+func (n *Node) saveState(ctx context.Context) error {
+	var state nodeState
+	n.actor.run(func(n *Node) {
+		state = nodeState{n.Address, append([]Address{}, n.Successors...)}
+	})
+	if sameAddresses(state.Successors, n.savedSuccessors) {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(n.config.DataDir, stateFile)
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("saving node: %v", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("saving node: %v", err)
+	}
+	n.savedSuccessors = state.Successors
+	return nil
+}
+
+// Hand the items a restarted node no longer owns to their owners once it knows its predecessor.
+// Items are sent a whole owner's range at a time, and kept only if the node replicates that range.
+func (n *Node) reconcile(ctx context.Context) {
+	// The owned range is only known once the predecessor that notified the node also has it as its successor.
+	// Until then a node that joined while this one was down can be missing between them.
+	var items map[Key]Siblings
+	var err error
+	for items == nil {
+		var predecessor Address
+		n.actor.run(func(n *Node) {
+			predecessor = n.Predecessor
+		})
+		var links NodeLink
+		if predecessor != "" && n.client.call(ctx, predecessor, "NodeActor.GetNodeLinks", None{}, &links) == nil &&
+			len(links.Successors) > 0 && links.Successors[0] == n.Address {
+			n.actor.run(func(n *Node) {
+				if n.Predecessor != predecessor {
+					return
+				}
+				if items, err = n.Data.Snapshot(); err != nil {
+					return
+				}
+				for key := range items {
+					if n.owns(key) {
+						delete(items, key)
+					}
+				}
+			})
+		}
+		if err != nil {
+			n.logger.Printf("reconcile: %v", err)
+			return
+		}
+		if items == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(n.config.StabilizeInterval):
+			}
+		}
+	}
+
+	for len(items) > 0 {
+		var first Key
+		for first = range items {
+			break
+		}
+		owner, err := n.client.Lookup(ctx, first)
+		if err != nil {
+			n.logger.Printf("reconcile: finding owner of %s: %v", string(first), err)
+			return
+		}
+		if owner == n.Address {
+			// Either the node took the key over since, or lookups have not caught up with its predecessor yet
+			owned := false
+			n.actor.run(func(n *Node) {
+				owned = n.owns(first)
+			})
+			if owned {
+				delete(items, first)
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(n.config.StabilizeInterval):
+			}
+			continue
+		}
+		var links NodeLink
+		if err := n.client.call(ctx, owner, "NodeActor.GetNodeLinks", None{}, &links); err != nil {
+			n.logger.Printf("reconcile: %v", err)
+			return
+		}
+		// Everything in the owner's range goes in one batch
+		batch := make(map[Key]Siblings)
+		for key, siblings := range items {
+			if key == first || (links.Predecessor != "" && between(links.Predecessor.hashed(), key.hashed(), owner.hashed(), true)) {
+				batch[key] = siblings
+				delete(items, key)
+			}
+		}
+		if err := n.client.call(ctx, owner, "NodeActor.PutAll", batch, &None{}); err != nil {
+			n.logger.Printf("reconcile: handing off to %s: %v", owner, err)
+			return
+		}
+		replica := false
+		for i, successor := range links.Successors {
+			replica = replica || (i < n.config.Replicas-1 && successor == n.Address)
+		}
+		if !replica {
+			n.actor.run(func(n *Node) {
+				for key := range batch {
+					if err = n.Data.Delete(key); err != nil {
+						return
+					}
+				}
+			})
+			if err != nil {
+				n.logger.Printf("reconcile: %v", err)
+				return
+			}
+		}
+		n.logger.Printf("reconcile: handed %d items to %s, kept copies: %v", len(batch), owner, replica)
+	}
+}
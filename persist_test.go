@@ -0,0 +1,139 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A ring whose nodes save to their own data directories, which are returned by address
+func persistentRing(t *testing.T, network *MemoryNetwork, count int, replicas int) ([]*Node, map[Address]string) {
+	dir := t.TempDir()
+	dirs := map[Address]string{}
+	nodes := testRing(t, network, count, func(cfg *Config) {
+		address := cfg.Transport.(*memoryTransport).address
+		dirs[address] = filepath.Join(dir, string(address))
+		cfg.DataDir = dirs[address]
+		cfg.Replicas = replicas
+	})
+	return nodes, dirs
+}
+
+// Restart a stopped node from its data directory, stopping it again when the test ends
+func restart(t *testing.T, network *MemoryNetwork, address Address, dir string, replicas int) *Node {
+	t.Helper()
+	cfg := testConfig(network, address)
+	cfg.DataDir = dir
+	cfg.Replicas = replicas
+	n, err := Restart(cfg)
+	if err != nil {
+		t.Fatalf("restarting %s: %v", address, err)
+	}
+	t.Cleanup(func() { n.stopNode() })
+	return n
+}
+
+// Wait until a node has saved its successors, so it can be restarted
+func waitForSave(t *testing.T, n *Node) {
+	t.Helper()
+	eventually(t, fmt.Sprintf("%s to save its successors", n.Address), func() bool {
+		state, err := loadState(n.config.DataDir)
+		return err == nil && len(state.Successors) > 0 && state.Successors[0] != n.Address
+	})
+}
+
+func TestRestartRejoins(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes, dirs := persistentRing(t, network, 3, 3)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		if err := client.PutWith(ctx, Key(fmt.Sprint("key", i)), fmt.Sprint(i), Consistency{W: 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stopped := nodes[1]
+	var err error
+	stopped.actor.run(func(n *Node) {
+		err = n.holdHint(Hint{Owner: "elsewhere", Request: PutRequest{KeyValue: KeyValue{Key: "hinted", Value: []byte("v")}}, Received: time.Now()})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForSave(t, stopped)
+	stopped.stopNode()
+	waitForRing(t, []*Node{nodes[0], nodes[2]})
+
+	restarted := restart(t, network, stopped.Address, dirs[stopped.Address], 3)
+	if restarted.Hash.Cmp(stopped.Hash) != 0 {
+		t.Errorf("restarted with hash %v instead of %v", restarted.Hash, stopped.Hash)
+	}
+	waitForRing(t, []*Node{nodes[0], restarted, nodes[2]})
+	// A copy handed off while the owner's successor list was still catching up is dropped, and comes back once the owner replicates
+	for i := 0; i < 20; i++ {
+		key := Key(fmt.Sprint("key", i))
+		eventually(t, fmt.Sprintf("the restarted node to hold %s", string(key)), func() bool {
+			return holds(restarted, key, fmt.Sprint(i))
+		})
+	}
+	if hints := heldHints(restarted); len(hints) != 1 || hints[0].Request.Key != "hinted" {
+		t.Errorf("restarted node holds hints %v", hints)
+	}
+}
+
+func TestRestartHandsOffItems(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes, dirs := persistentRing(t, network, 3, 1)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		if err := client.Put(ctx, Key(fmt.Sprint("key", i)), fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// While a node is down, another joins in the middle of its range
+	ordered := byHash(nodes)
+	stopped, predecessor := ordered[1], ordered[0]
+	waitForSave(t, stopped)
+	stopped.stopNode()
+	waitForRing(t, []*Node{ordered[0], ordered[2]})
+	var address Address
+	for i := 0; address == ""; i++ {
+		candidate := Address(fmt.Sprint("extra", i))
+		if between(predecessor.Hash, candidate.hashed(), stopped.Hash, false) {
+			address = candidate
+		}
+	}
+	cfg := testConfig(network, address)
+	cfg.Replicas = 1
+	joined, err := Join(cfg, predecessor.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { joined.stopNode() })
+	waitForRing(t, []*Node{ordered[0], joined, ordered[2]})
+
+	restarted := restart(t, network, stopped.Address, dirs[stopped.Address], 1)
+	live := []*Node{ordered[0], restarted, ordered[2], joined}
+	waitForRing(t, live)
+	moved := 0
+	for i := 0; i < 30; i++ {
+		key := Key(fmt.Sprint("key", i))
+		owner := ownerOf(live, key)[0]
+		if owner != restarted && owner != joined {
+			continue
+		}
+		if owner == joined {
+			moved++
+		}
+		eventually(t, fmt.Sprintf("%s to be held by its owner only", string(key)), func() bool {
+			return holds(owner, key, fmt.Sprint(i)) && (owner == restarted || stored(restarted, key) == nil)
+		})
+	}
+	if moved == 0 {
+		t.Error("no reloaded key belongs to the node that joined")
+	}
+}
@@ -0,0 +1,163 @@
+package chord
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+const (
+	healthCheckAfter = 10 * time.Second // Idle connections are pinged before being reused after this long
+)
+
+type (
+	// Keeps one open RPC client per peer so repeated calls skip the connection handshake
+	pool struct {
+		mu      sync.Mutex
+		clients map[Address]*pooledClient
+	}
+
+	// An open RPC client and when it was last used successfully
+	pooledClient struct {
+		*rpc.Client
+		lastUsed time.Time
+	}
+)
+
+func newPool() *pool {
+	return &pool{
+		clients: make(map[Address]*pooledClient),
+	}
+}
+
+// The RPC call, reusing a pooled connection to the address if there is one
+func (p *pool) call(ctx context.Context, address Address, method string, request interface{}, reply interface{}) error {
+	client, err := p.get(ctx, address)
+	if err != nil {
+		return err
+	}
+	if err := invoke(ctx, client.Client, method, request, reply); err != nil {
+		// Errors returned by the method itself leave the connection usable
+		if _, ok := err.(rpc.ServerError); !ok {
+			p.evict(address, client)
+		}
+		return err
+	}
+	p.mu.Lock()
+	client.lastUsed = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// Get a healthy client for the address, dialing a new one if needed
+func (p *pool) get(ctx context.Context, address Address) (*pooledClient, error) {
+	p.mu.Lock()
+	client, exists := p.clients[address]
+	var idle time.Duration
+	if exists {
+		idle = time.Since(client.lastUsed)
+	}
+	p.mu.Unlock()
+
+	if exists {
+		if idle < healthCheckAfter {
+			return client, nil
+		}
+		// Make sure the connection survived sitting idle
+		var success bool
+		if err := invoke(ctx, client.Client, "NodeActor.Ping", None{}, &success); err == nil && success {
+			return client, nil
+		}
+		p.evict(address, client)
+	}
+
+	conn, err := dial(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Another caller may have dialed at the same time
+	if existing, exists := p.clients[address]; exists {
+		conn.Close()
+		return existing, nil
+	}
+	client = &pooledClient{conn, time.Now()}
+	p.clients[address] = client
+	return client, nil
+}
+
+// Remove a broken client from the pool
+func (p *pool) evict(address Address, client *pooledClient) {
+	p.mu.Lock()
+	if p.clients[address] == client {
+		delete(p.clients, address)
+	}
+	p.mu.Unlock()
+	client.Close()
+}
+
+// Close every pooled connection
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for address, client := range p.clients {
+		client.Close()
+		delete(p.clients, address)
+	}
+}
+
+// A listener that remembers accepted connections so they can all be cut when the node stops.
+// Otherwise pooled connections from other nodes would keep being served after the listener closes.
+type trackingListener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns map[*trackedConn]None
+}
+
+// A connection that removes itself from its listener when closed
+type trackedConn struct {
+	net.Conn
+	listener *trackingListener
+}
+
+func newTrackingListener(listener net.Listener) *trackingListener {
+	return &trackingListener{
+		Listener: listener,
+		conns:    make(map[*trackedConn]None),
+	}
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	tracked := &trackedConn{conn, l}
+	l.mu.Lock()
+	l.conns[tracked] = None{}
+	l.mu.Unlock()
+	return tracked, nil
+}
+
+// Close stops accepting and closes every open connection
+func (l *trackingListener) Close() error {
+	err := l.Listener.Close()
+	l.mu.Lock()
+	conns := l.conns
+	l.conns = make(map[*trackedConn]None)
+	l.mu.Unlock()
+	for conn := range conns {
+		conn.Conn.Close()
+	}
+	return err
+}
+
+func (c *trackedConn) Close() error {
+	c.listener.mu.Lock()
+	delete(c.listener.conns, c)
+	c.listener.mu.Unlock()
+	return c.Conn.Close()
+}
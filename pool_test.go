@@ -0,0 +1,126 @@
+package chord
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/rpc"
+	"testing"
+	"time"
+)
+
+// Settings for a node on the RPC transport listening on a free local port, maintained as quickly as a simulated one
+func tcpConfig() Config {
+	cfg := DefaultConfig()
+	cfg.BindAddress = "127.0.0.1"
+	cfg.Port = 0
+	cfg.StabilizeInterval = 10 * time.Millisecond
+	cfg.FixFingersInterval = 5 * time.Millisecond
+	cfg.CheckPredecessorInterval = 10 * time.Millisecond
+	cfg.AntiEntropyInterval = time.Hour
+	cfg.HintInterval = time.Hour
+	cfg.ExpireInterval = time.Hour
+	cfg.CallTimeout = 200 * time.Millisecond
+	cfg.LookupTimeout = 2 * time.Second
+	cfg.Logger = log.New(ioutil.Discard, "", 0)
+	return cfg
+}
+
+// Start a ring of one node on the RPC transport, stopped when the test ends
+func tcpNode(t *testing.T) *Node {
+	t.Helper()
+	n, err := Create(tcpConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { n.stopNode() })
+	return n
+}
+
+// The client pooled for an address, if there is one
+func pooled(p *pool, address Address) *pooledClient {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.clients[address]
+}
+
+func ping(p *pool, address Address) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var success bool
+	return p.call(ctx, address, "NodeActor.Ping", None{}, &success)
+}
+
+func TestPoolReusesConnections(t *testing.T) {
+	n := tcpNode(t)
+	p := newPool()
+	defer p.close()
+	if err := ping(p, n.Address); err != nil {
+		t.Fatal(err)
+	}
+	first := pooled(p, n.Address)
+	if err := ping(p, n.Address); err != nil {
+		t.Fatal(err)
+	}
+	if first == nil || pooled(p, n.Address) != first {
+		t.Error("a second call did not reuse the pooled connection")
+	}
+
+	p.close()
+	if pooled(p, n.Address) != nil {
+		t.Error("closing the pool kept a connection")
+	}
+	var success bool
+	if err := first.Call("NodeActor.Ping", None{}, &success); err != rpc.ErrShutdown {
+		t.Errorf("call on a connection of a closed pool: %v", err)
+	}
+}
+
+func TestPoolChecksIdleConnections(t *testing.T) {
+	n := tcpNode(t)
+	p := newPool()
+	defer p.close()
+	if err := ping(p, n.Address); err != nil {
+		t.Fatal(err)
+	}
+	// A connection that broke while it sat idle is replaced rather than failing the next call
+	idle := pooled(p, n.Address)
+	idle.Close()
+	p.mu.Lock()
+	idle.lastUsed = time.Now().Add(-healthCheckAfter)
+	p.mu.Unlock()
+	if err := ping(p, n.Address); err != nil {
+		t.Fatalf("call after the idle connection broke: %v", err)
+	}
+	if replaced := pooled(p, n.Address); replaced == nil || replaced == idle {
+		t.Error("the broken idle connection was kept")
+	}
+}
+
+func TestPoolEvictsBrokenConnections(t *testing.T) {
+	n := tcpNode(t)
+	p := newPool()
+	defer p.close()
+	if err := ping(p, n.Address); err != nil {
+		t.Fatal(err)
+	}
+	// Errors returned by the method keep the connection
+	kept := pooled(p, n.Address)
+	var siblings Siblings
+	err := p.call(context.Background(), n.Address, "NodeActor.Get", GetRequest{Key: "missing"}, &siblings)
+	if _, ok := err.(rpc.ServerError); !ok {
+		t.Fatalf("get of a missing key: got %v, expected an error from the method", err)
+	}
+	if pooled(p, n.Address) != kept {
+		t.Error("an error from the method evicted the connection")
+	}
+
+	// Stopping the node cuts the connection, which is evicted on the next call
+	n.stopNode()
+	if err := ping(p, n.Address); err == nil {
+		t.Fatal("call to a stopped node succeeded")
+	}
+	if pooled(p, n.Address) != nil {
+		t.Error("the broken connection was kept")
+	}
+}
@@ -0,0 +1,72 @@
+package chord
+
+import (
+	"fmt"
+)
+
+// Consistency sets how many replicas a request involves. Zero fields use the owner's configured defaults.
+type Consistency struct {
+	N int // Replicas contacted, counting the owner
+	R int // Replies needed for a read to succeed, counting the owner
+	W int // Acknowledgements needed for a write to succeed, counting the owner
+}
+
+// A reply from one replica contacted by a coordinator
+type replicaReply struct {
+	address Address
+	reply   interface{}
+	err     error
+}
+
+// Fill in the defaults for a request's consistency and check that the quorums can be met
+func (n *Node) consistency(c Consistency) (Consistency, error) {
+	if c.N <= 0 {
+		c.N = n.config.Replicas
+	}
+	if c.R <= 0 {
+		c.R = n.config.ReadQuorum
+	}
+	if c.W <= 0 {
+		c.W = n.config.WriteQuorum
+	}
+	if c.R > c.N || c.W > c.N {
+		return c, fmt.Errorf("quorum larger than the %d replicas contacted (R=%d, W=%d)", c.N, c.R, c.W)
+	}
+	return c, nil
+}
+
+// Make the same call on every target in parallel, returning the successful replies once
+// needed of them have arrived or every call has finished. Calls still in flight carry on in the background.
+func (n *Node) callReplicas(targets []Address, method string, request interface{}, newReply func() interface{}, needed int) ([]replicaReply, error) {
+	results := make(chan replicaReply, len(targets))
+	for _, target := range targets {
+		go func(target Address) {
+			reply := newReply()
+			err := n.client.call(n.ctx, target, method, request, reply)
+			if err != nil {
+				n.logger.Printf("%s on replica %s: %v", method, target, err)
+			}
+			results <- replicaReply{target, reply, err}
+		}(target)
+	}
+
+	replies := []replicaReply{}
+	for range targets {
+		if len(replies) >= needed {
+			break
+		}
+		if result := <-results; result.err == nil {
+			replies = append(replies, result)
+		}
+	}
+	if len(replies) < needed {
+		return replies, fmt.Errorf("quorum not reached: %d of %d replicas replied", len(replies)+1, needed+1)
+	}
+	return replies, nil
+}
+
+// Send a write to the replicas and wait for the write quorum, counting the owner's own write
+func (n *Node) awaitWrites(c Consistency, targets []Address, method string, request interface{}) error {
+	_, err := n.callReplicas(targets, method, request, func() interface{} { return &None{} }, c.W-1)
+	return err
+}
@@ -0,0 +1,58 @@
+package chord
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// The replicas of a key are partitioned from its owner, which keeps them in its successor list
+// since ring maintenance only talks to the first successor.
+
+func TestWriteQuorum(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 5, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.PutWith(ctx, "key", "v1", Consistency{N: 3, W: 3}); err != nil {
+		t.Fatalf("with every replica up: %v", err)
+	}
+
+	replicas := ownerOf(nodes, "key")
+	network.Partition(replicas[0].Address, replicas[2].Address)
+	err := client.PutWith(ctx, "key", "v2", Consistency{N: 3, W: 3})
+	if err == nil || !strings.Contains(err.Error(), "quorum not reached") {
+		t.Errorf("W=3 with a replica cut off: got %v, expected the quorum to fail", err)
+	}
+	if err := client.PutWith(ctx, "key", "v3", Consistency{N: 3, W: 2}); err != nil {
+		t.Errorf("W=2 with a replica cut off: %v", err)
+	}
+	if !holds(replicas[1], "key", "v3") {
+		t.Errorf("replica holds %v after a W=2 put", stored(replicas[1], "key"))
+	}
+}
+
+func TestReadQuorum(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 5, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.PutWith(ctx, "key", "value", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := client.GetWith(ctx, "key", Consistency{R: 3}); err != nil || value != "value" {
+		t.Errorf("R=3: got %q, %v", value, err)
+	}
+
+	replicas := ownerOf(nodes, "key")
+	network.Partition(replicas[0].Address, replicas[2].Address)
+	if value, err := client.GetWith(ctx, "key", Consistency{N: 3, R: 2}); err != nil || value != "value" {
+		t.Errorf("R=2 with a replica cut off: got %q, %v", value, err)
+	}
+	if _, err := client.GetWith(ctx, "key", Consistency{N: 3, R: 3}); err == nil {
+		t.Error("R=3 succeeded with a replica cut off")
+	}
+	if _, err := client.GetWith(ctx, "key", Consistency{N: 3, R: 4}); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("R larger than N: got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package chord
+
+// Read repair: a quorum read compares the versions returned by each replica with the merged result
+// and writes the merged versions back to any replica, including the owner, that was missing some of them.
+
+// Whether two sets of siblings hold the same items
+func (s Siblings) equal(other Siblings) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for _, item := range s {
+		found := false
+		for _, o := range other {
+			found = found || item.identical(o)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Write the latest versions of a key back to the owner and the replicas whose replies were out of date.
+// Replicas are updated in the background so the read is not held up.
+func (n *Node) readRepair(key Key, latest Siblings, local Siblings, replies []replicaReply) {
+	if !local.equal(latest) {
+		var err error
+		n.actor.run(func(n *Node) {
+			err = n.merge(key, latest...)
+		})
+		if err != nil {
+			n.logger.Printf("read repair of %s: %v", string(key), err)
+		}
+	}
+	stale := []Address{}
+	for _, reply := range replies {
+		if !reply.reply.(*Siblings).equal(latest) {
+			stale = append(stale, reply.address)
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+	n.logger.Printf("read repair: updating %d stale replicas of %s", len(stale), string(key))
+	go n.sendToReplicas(n.ctx, stale, "NodeActor.PutAll", map[Key]Siblings{key: latest})
+}
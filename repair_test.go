@@ -0,0 +1,32 @@
+package chord
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReadRepair(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 5, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	replicas := ownerOf(nodes, "key")
+	if err := client.PutWith(ctx, "key", "v1", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+	network.Partition(replicas[0].Address, replicas[2].Address)
+	if err := client.PutWith(ctx, "key", "v2", Consistency{W: 2}); err != nil {
+		t.Fatal(err)
+	}
+	network.Heal()
+	if !holds(replicas[2], "key", "v1") {
+		t.Fatalf("cut off replica holds %v", stored(replicas[2], "key"))
+	}
+
+	if value, err := client.GetWith(ctx, "key", Consistency{R: 3}); err != nil || value != "v2" {
+		t.Fatalf("quorum read: got %q, %v", value, err)
+	}
+	eventually(t, "the stale replica to be repaired", func() bool {
+		return holds(replicas[2], "key", "v2")
+	})
+}
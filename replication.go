@@ -0,0 +1,75 @@
+package chord
+
+import (
+	"context"
+	"sync"
+)
+
+// Replication keeps a copy of every item on the first Replicas-1 successors of the node that owns it.
+// Replicas live in Data next to owned items; which items a node owns follows from its predecessor.
+// When a node fails its successor already holds copies of its items and becomes their owner as soon as lookups route there.
+
+// Whether this node is responsible for a key. Everything is owned until the predecessor is known.
+func (n *Node) owns(key Key) bool {
+	return n.Predecessor == "" || between(n.Predecessor.hashed(), key.hashed(), n.Hash, true)
+}
+
+// The items this node is responsible for
+func (n *Node) ownedData() (map[Key]Siblings, error) {
+	if n.Predecessor == "" {
+		return n.Data.Snapshot()
+	}
+	return n.Data.Range(n.Predecessor.hashed(), n.Hash)
+}
+
+// The first count distinct successors, which hold copies of this node's items
+func (n *Node) replicaTargets(count int) []Address {
+	targets := []Address{}
+	seen := map[Address]bool{n.Address: true}
+	for _, successor := range n.Successors {
+		if len(targets) >= count {
+			break
+		}
+		if !seen[successor] {
+			seen[successor] = true
+			targets = append(targets, successor)
+		}
+	}
+	return targets
+}
+
+// Copy all owned items to the replicas, e.g. after the successor list changes or a predecessor fails
+func (n *Node) replicate(ctx context.Context) {
+	var owned map[Key]Siblings
+	var targets []Address
+	var err error
+	n.actor.run(func(n *Node) {
+		owned, err = n.ownedData()
+		targets = n.replicaTargets(n.config.Replicas - 1)
+	})
+	if err != nil {
+		n.logger.Printf("replicate: %v", err)
+		return
+	}
+	if len(owned) == 0 || len(targets) == 0 {
+		return
+	}
+	n.logger.Printf("replicate: copying %d items to %d replicas", len(owned), len(targets))
+	n.sendToReplicas(ctx, targets, "NodeActor.PutAll", owned)
+}
+
+// Make the same call on every replica in parallel, logging failures.
+// A replica that misses an update is brought back in line the next time replicas are refreshed.
+func (n *Node) sendToReplicas(ctx context.Context, targets []Address, method string, request interface{}) {
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target Address) {
+			defer wg.Done()
+			if err := n.client.call(ctx, target, method, request, &None{}); err != nil {
+				n.logger.Printf("replicating to %s: %v", target, err)
+			}
+		}(target)
+	}
+	wg.Wait()
+}
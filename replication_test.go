@@ -0,0 +1,75 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Fail the test unless a condition holds within a few seconds
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Whether a node stores exactly one version of a key with a value
+func holds(n *Node, key Key, value string) bool {
+	siblings := stored(n, key)
+	return len(siblings) == 1 && string(siblings[0].Value) == value
+}
+
+func TestReplicasHoldCopies(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 5, nil)
+	client := testClient(t, network, nodes)
+	for i := 0; i < 20; i++ {
+		key := Key(fmt.Sprint("key", i))
+		if err := client.Put(context.Background(), key, fmt.Sprint(i)); err != nil {
+			t.Fatalf("putting %s: %v", string(key), err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		key := Key(fmt.Sprint("key", i))
+		for _, n := range ownerOf(nodes, key)[:3] {
+			eventually(t, fmt.Sprintf("%s to hold %s", n.Address, string(key)), func() bool {
+				return holds(n, key, fmt.Sprint(i))
+			})
+		}
+	}
+}
+
+func TestKeysSurviveOwnerCrash(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 5, nil)
+	client := testClient(t, network, nodes)
+	if err := client.PutWith(context.Background(), "key", "value", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+	owner := ownerOf(nodes, "key")[0]
+	network.Crash(owner.Address)
+	live := []*Node{}
+	for _, n := range nodes {
+		if n != owner {
+			live = append(live, n)
+		}
+	}
+	waitForRing(t, live)
+
+	client = testClient(t, network, live)
+	if value, err := client.Get(context.Background(), "key"); err != nil || value != "value" {
+		t.Fatalf("after the owner crashed: got %q, %v", value, err)
+	}
+	// The new owner restores the replication factor
+	for _, n := range ownerOf(live, "key")[:3] {
+		eventually(t, fmt.Sprintf("%s to hold key", n.Address), func() bool {
+			return holds(n, "key", "value")
+		})
+	}
+}
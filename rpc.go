@@ -1,33 +1,62 @@
-package main
+package chord
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"math/big"
 	"net"
 	"net/http"
 	"net/rpc"
+	"time"
 )
 
 const (
 	maxRequests = 32 // Maximum number of requests a single lookup can generate
 )
 
-// Start the RPC server on the node
+var (
+	// ErrNoSuchKey is returned when a key is not stored in the ring
+	ErrNoSuchKey = errors.New("no such key")
+	// ErrConditionFailed is returned when a conditional write finds the key in a different state than expected
+	ErrConditionFailed = errors.New("condition not met")
+	// ErrValueTooLarge is returned when a value is larger than the owner of its key accepts
+	ErrValueTooLarge = errors.New("value too large")
+	// ErrReservedKey is returned when a key is in the namespace the ring keeps for its own use
+	ErrReservedKey = errors.New("keys starting with a NUL byte are reserved")
+	// ErrLargeValue is returned when a value split into chunks by PutLarge is read as a plain value
+	ErrLargeValue = errors.New("value is stored in chunks, read it with GetLarge")
+)
+
+// Start serving RPCs to the node over its transport, and over JSON-RPC if enabled
 func (n *Node) startNode() error {
-	// Make sure port isn't in use frst
-	listener, err := net.Listen("tcp", ":"+fmt.Sprint(localPort))
-	if err != nil {
-		return fmt.Errorf("listen error: %v", err)
+	n.actor = n.startActor()
+	if err := n.transport.Serve(n.actor); err != nil {
+		return err
+	}
+	if n.config.JSONRPCPort != 0 {
+		return n.startJSONRPC(n.actor)
 	}
-	actor := n.startActor()
-	rpc.Register(actor)
-	rpc.HandleHTTP()
-	go http.Serve(listener, nil)
 	return nil
 }
 
+// Stop the RPC servers and all background tasks
+func (n *Node) stopNode() error {
+	n.cancel()
+	if n.jsonListener != nil {
+		n.jsonListener.Close()
+	}
+	if n.httpServer != nil {
+		n.httpServer.Close()
+	}
+	if err := n.transport.Close(); err != nil {
+		return err
+	}
+	return n.Data.Close()
+}
+
 func (n *Node) startActor() NodeActor {
 	ch := make(chan handler)
 	// Launch actor channel
@@ -49,20 +78,69 @@ func (a NodeActor) run(f handler) {
 	<-done
 }
 
-// The RPC call
-func call(address Address, method string, request interface{}, reply interface{}) error {
-	client, err := rpc.DialHTTP("tcp", string(address))
+// A one-off RPC call on a new connection
+func call(ctx context.Context, address Address, method string, request interface{}, reply interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultConfig().CallTimeout)
+	defer cancel()
+
+	client, err := dial(ctx, address)
 	if err != nil {
 		return err
 	}
 	defer client.Close()
 
-	// Synchronous call
-	if err := client.Call(method, request, reply); err != nil {
-		return err
+	return invoke(ctx, client, method, request, reply)
+}
+
+// Connect to the RPC server of a node, giving up when the context is done
+func dial(ctx context.Context, address Address) (*rpc.Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", string(address))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, contextError(ctx, fmt.Sprintf("dialing %s", string(address)))
+		}
+		return nil, err
+	}
+	// Bound the HTTP handshake by the context as well
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// Same handshake as rpc.DialHTTP
+	io.WriteString(conn, "CONNECT "+rpc.DefaultRPCPath+" HTTP/1.0\n\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	if err != nil {
+		conn.Close()
+		// The connection deadline can fire just before the context notices
+		if ne, ok := err.(net.Error); ctx.Err() != nil || (ok && ne.Timeout()) {
+			return nil, fmt.Errorf("dialing %s: timed out", string(address))
+		}
+		return nil, err
+	}
+	if resp.Status != "200 Connected to Go RPC" {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected HTTP response: %s", resp.Status)
 	}
+	conn.SetDeadline(time.Time{})
+	return rpc.NewClient(conn), nil
+}
 
-	return nil
+// Make a call on an open client, giving up when the context is done
+func invoke(ctx context.Context, client *rpc.Client, method string, request interface{}, reply interface{}) error {
+	select {
+	case call := <-client.Go(method, request, reply, make(chan *rpc.Call, 1)).Done:
+		return call.Error
+	case <-ctx.Done():
+		return contextError(ctx, fmt.Sprintf("calling %s", method))
+	}
+}
+
+// Describe why a context ended an operation
+func contextError(ctx context.Context, operation string) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s: timed out", operation)
+	}
+	return fmt.Errorf("%s: %v", operation, ctx.Err())
 }
 
 // Ping simply tests an RPC connection
@@ -90,12 +168,26 @@ func (a NodeActor) FindSuccessor(id *big.Int, result *AddressResult) error {
 
 // Notify signals a node that another node thinks it should be its predecessor
 func (a NodeActor) Notify(address Address, _ *None) error {
+	var node *Node
+	promoted := false
 	a.run(func(n *Node) {
 		if n.Predecessor == "" || between(n.Predecessor.hashed(), address.hashed(), n.Hash, false) {
-			log.Println("Notify: found new predecessor")
+			n.logger.Println("Notify: found new predecessor")
+			// Without a predecessor the old one failed (or this node just started), so any
+			// replicas between the new predecessor and this node are now owned here
+			promoted = n.Predecessor == ""
 			n.Predecessor = address
+			node = n
+			// Polls on keys the new predecessor now owns have to move to it
+			for key := range n.watches {
+				n.notify(key)
+			}
 		}
 	})
+	if promoted {
+		// Restore the replication factor for the promoted items without holding up the notifier
+		go node.replicate(node.ctx)
+	}
 	return nil
 }
 
@@ -108,66 +200,376 @@ func (a NodeActor) GetNodeLinks(request None, links *NodeLink) error {
 	return nil
 }
 
-// Put adds an item to the database
-func (a NodeActor) Put(kv KeyValue, _ *None) error {
+// Put stores a new version of a key and copies it to the replicas, succeeding once the write quorum has it.
+// The new version replaces the versions the writer has seen, any others are kept as siblings.
+// A conditional put is checked against the stored versions in the same step as the write, so it is atomic on the owner.
+func (a NodeActor) Put(request PutRequest, _ *None) error {
+	var err error
+	var node *Node
+	var c Consistency
+	var targets []Address
+	var item Item
 	a.run(func(n *Node) {
-		n.Data[kv.Key] = kv.Value
+		node = n
+		if c, err = n.consistency(request.Consistency); err != nil {
+			return
+		}
+		if err = request.check(n.config); err != nil {
+			return
+		}
+		var existing Siblings
+		if existing, err = n.Data.Get(request.Key); err != nil {
+			return
+		}
+		if err = request.Condition.check(existing.live(time.Now())); err != nil {
+			return
+		}
+		if item, err = n.write(request); err != nil {
+			return
+		}
+		targets = n.replicaTargets(c.N - 1)
 	})
-	return nil
+	if err != nil {
+		return err
+	}
+	return node.awaitWrites(c, targets, "NodeActor.PutAll", map[Key]Siblings{request.Key: {item}})
 }
 
-// Get retrieves the value of a key in the database
-func (a NodeActor) Get(key Key, value *string) error {
+// Get retrieves the live versions of a key in the database once the read quorum has replied.
+// The versions held by the owner and the replicas that replied are merged, so concurrent versions come back as siblings,
+// and any of them that were missing versions are repaired.
+func (a NodeActor) Get(request GetRequest, siblings *Siblings) error {
 	var err error
+	var node *Node
+	var c Consistency
+	var targets []Address
+	var local Siblings
 	a.run(func(n *Node) {
-		if val, exists := n.Data[key]; exists {
-			*value = val
-		} else {
-			err = errors.New("no such key")
+		node = n
+		if c, err = n.consistency(request.Consistency); err != nil {
+			return
+		}
+		if local, err = n.Data.Get(request.Key); err != nil {
+			return
+		}
+		targets = n.replicaTargets(c.N - 1)
+	})
+	if err != nil {
+		return err
+	}
+	var replies []replicaReply
+	if c.R > 1 {
+		newReply := func() interface{} { return &Siblings{} }
+		if replies, err = node.callReplicas(targets, "NodeActor.GetReplica", request.Key, newReply, c.R-1); err != nil {
+			return err
 		}
+	}
+	latest := local
+	for _, reply := range replies {
+		latest = latest.merge(*reply.reply.(*Siblings)...)
+	}
+	if len(replies) > 0 {
+		node.readRepair(request.Key, latest, local, replies)
+	}
+	if *siblings = latest.live(time.Now()); len(*siblings) == 0 {
+		return ErrNoSuchKey
+	}
+	return nil
+}
+
+// GetReplica returns this node's own versions of a key without involving other replicas
+func (a NodeActor) GetReplica(key Key, siblings *Siblings) error {
+	var err error
+	a.run(func(n *Node) {
+		*siblings, err = n.Data.Get(key)
 	})
 	return err
 }
 
-// Delete removes a key and its associated value from the database
-func (a NodeActor) Delete(key Key, value *string) error {
+// Delete replaces every version of a key with a tombstone and copies it to the replicas, succeeding once the write quorum has it.
+// A conditional delete is checked against the stored versions in the same step as the delete.
+func (a NodeActor) Delete(request DeleteRequest, siblings *Siblings) error {
 	var err error
+	var node *Node
+	var c Consistency
+	var targets []Address
+	var tombstone Item
 	a.run(func(n *Node) {
-		if val, exists := n.Data[key]; exists {
-			*value = val
-			delete(n.Data, key)
-		} else {
-			err = errors.New("no such key")
+		node = n
+		if c, err = n.consistency(request.Consistency); err != nil {
+			return
+		}
+		var existing Siblings
+		if existing, err = n.Data.Get(request.Key); err != nil {
+			return
+		}
+		live := existing.live(time.Now())
+		if len(live) == 0 {
+			err = ErrNoSuchKey
+			return
+		}
+		if err = request.Condition.check(live); err != nil {
+			return
+		}
+		if tombstone, err = n.delete(request.Key); err != nil {
+			return
+		}
+		*siblings = live
+		targets = n.replicaTargets(c.N - 1)
+	})
+	if err != nil {
+		return err
+	}
+	return node.awaitWrites(c, targets, "NodeActor.PutAll", map[Key]Siblings{request.Key: {tombstone}})
+}
+
+// MultiPut stores new versions of many keys and copies them to the replicas in one call each, succeeding once the write quorum has them.
+// Each value replaces every version of its key.
+func (a NodeActor) MultiPut(request MultiPutRequest, _ *None) error {
+	var err error
+	var node *Node
+	var c Consistency
+	var targets []Address
+	written := make(map[Key]Siblings)
+	a.run(func(n *Node) {
+		node = n
+		if c, err = n.consistency(request.Consistency); err != nil {
+			return
+		}
+		for _, value := range request.Items {
+			if len(value) > n.config.MaxValueSize {
+				err = ErrValueTooLarge
+				return
+			}
+		}
+		for key, value := range request.Items {
+			var item Item
+			if item, err = n.write(PutRequest{KeyValue: KeyValue{key, value}}); err != nil {
+				return
+			}
+			written[key] = Siblings{item}
+		}
+		targets = n.replicaTargets(c.N - 1)
+	})
+	if err != nil {
+		return err
+	}
+	return node.awaitWrites(c, targets, "NodeActor.PutAll", written)
+}
+
+// MultiGet retrieves the live versions of many keys once the read quorum has replied, leaving out keys that are not stored.
+// Each replica is asked for all of the keys in one call, and replicas that were missing versions are repaired.
+func (a NodeActor) MultiGet(request MultiKeyRequest, items *map[Key]Siblings) error {
+	var err error
+	var node *Node
+	var c Consistency
+	var targets []Address
+	local := make(map[Key]Siblings)
+	a.run(func(n *Node) {
+		node = n
+		if c, err = n.consistency(request.Consistency); err != nil {
+			return
+		}
+		for _, key := range request.Keys {
+			if local[key], err = n.Data.Get(key); err != nil {
+				return
+			}
+		}
+		targets = n.replicaTargets(c.N - 1)
+	})
+	if err != nil {
+		return err
+	}
+	var replies []replicaReply
+	if c.R > 1 {
+		newReply := func() interface{} { return &map[Key]Siblings{} }
+		if replies, err = node.callReplicas(targets, "NodeActor.GetReplicas", request.Keys, newReply, c.R-1); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	for key, siblings := range local {
+		latest := siblings
+		keyReplies := []replicaReply{}
+		for _, reply := range replies {
+			theirs := (*reply.reply.(*map[Key]Siblings))[key]
+			latest = latest.merge(theirs...)
+			keyReplies = append(keyReplies, replicaReply{reply.address, &theirs, nil})
+		}
+		if len(replies) > 0 {
+			node.readRepair(key, latest, siblings, keyReplies)
+		}
+		if live := latest.live(now); len(live) > 0 {
+			(*items)[key] = live
+		}
+	}
+	return nil
+}
+
+// GetReplicas returns this node's own versions of many keys without involving other replicas
+func (a NodeActor) GetReplicas(keys []Key, items *map[Key]Siblings) error {
+	var err error
+	a.run(func(n *Node) {
+		for _, key := range keys {
+			var siblings Siblings
+			if siblings, err = n.Data.Get(key); err != nil {
+				return
+			}
+			if siblings != nil {
+				(*items)[key] = siblings
+			}
 		}
 	})
 	return err
 }
 
-// PutAll adds all key/value pairs in a map to the local data
-func (a NodeActor) PutAll(data map[Key]string, _ *None) error {
+// MultiDelete replaces every version of many keys with tombstones and copies them to the replicas, succeeding once the write quorum has them.
+// The live versions of the deleted keys are returned, leaving out keys that were not stored.
+func (a NodeActor) MultiDelete(request MultiKeyRequest, items *map[Key]Siblings) error {
+	var err error
+	var node *Node
+	var c Consistency
+	var targets []Address
+	tombstones := make(map[Key]Siblings)
+	a.run(func(n *Node) {
+		node = n
+		if c, err = n.consistency(request.Consistency); err != nil {
+			return
+		}
+		now := time.Now()
+		for _, key := range request.Keys {
+			var existing Siblings
+			if existing, err = n.Data.Get(key); err != nil {
+				return
+			}
+			live := existing.live(now)
+			if len(live) == 0 {
+				continue
+			}
+			var tombstone Item
+			if tombstone, err = n.delete(key); err != nil {
+				return
+			}
+			(*items)[key] = live
+			tombstones[key] = Siblings{tombstone}
+		}
+		targets = n.replicaTargets(c.N - 1)
+	})
+	if err != nil || len(tombstones) == 0 {
+		return err
+	}
+	return node.awaitWrites(c, targets, "NodeActor.PutAll", tombstones)
+}
+
+// PutAll merges the versions of all keys in a map into the local data
+func (a NodeActor) PutAll(data map[Key]Siblings, _ *None) error {
 	var err error
 	a.run(func(n *Node) {
-		for key, value := range data {
-			n.Data[key] = value
+		for key, siblings := range data {
+			if err = n.merge(key, siblings...); err != nil {
+				return
+			}
 		}
 	})
 	return err
 }
 
-// GetAll gathers all key/value pairs from a node and transfers them to a newly joined node that they belong to
-func (a NodeActor) GetAll(newAddress Address, data *map[Key]string) error {
+// GetAll gathers all key/value pairs from a node and transfers them to a newly joined node that they belong to.
+// With replication the node stays a replica of the newly joined node, so it keeps copies.
+func (a NodeActor) GetAll(newAddress Address, data *map[Key]Siblings) error {
 	var err error
 	a.run(func(n *Node) {
-		for key, value := range n.Data {
-			if !between(newAddress.hashed(), key.hashed(), n.Hash, true) {
-				(*data)[key] = value
-				delete(n.Data, key)
+		// Everything outside (newAddress, n] now belongs to the new node
+		if *data, err = n.Data.Range(n.Hash, newAddress.hashed()); err != nil {
+			return
+		}
+		if n.config.Replicas <= 1 {
+			for key := range *data {
+				if err = n.Data.Delete(key); err != nil {
+					return
+				}
 			}
 		}
 	})
 	return err
 }
 
+// Scan lists the keys this node stores in a range, in the order of their hashes
+func (a NodeActor) Scan(request ScanRequest, reply *ScanReply) error {
+	var err error
+	a.run(func(n *Node) {
+		*reply, err = n.scan(request)
+	})
+	return err
+}
+
+// Watch holds a poll for a change to a key, replying once its versions differ from the ones the watcher has seen
+func (a NodeActor) Watch(request WatchRequest, reply *WatchReply) error {
+	var node *Node
+	a.run(func(n *Node) {
+		node = n
+	})
+	return node.watch(request, reply)
+}
+
+// BucketStats returns the stats of the keys this node stores in a range in each bucket, or in one bucket if named
+func (a NodeActor) BucketStats(request BucketStatsRequest, stats *map[string]BucketStats) error {
+	var err error
+	a.run(func(n *Node) {
+		*stats, err = n.bucketStats(request)
+	})
+	return err
+}
+
+// Hint holds a put for an owner that could not be reached until it can be delivered
+func (a NodeActor) Hint(hint Hint, _ *None) error {
+	if hint.Received.IsZero() {
+		hint.Received = time.Now()
+	}
+	var err error
+	a.run(func(n *Node) {
+		if err = hint.Request.check(n.config); err != nil {
+			return
+		}
+		if err = n.holdHint(hint); err != nil {
+			return
+		}
+		n.logger.Printf("holding hinted put of %s for %s", string(hint.Request.Key), hint.Owner)
+	})
+	return err
+}
+
+// MerkleHashes returns the hashes of the requested nodes of a Merkle tree over this node's items in a range
+func (a NodeActor) MerkleHashes(request MerkleRequest, hashes *[][]byte) error {
+	if request.Level < 0 || request.Level > merkleDepth {
+		return fmt.Errorf("no level %d in a Merkle tree of depth %d", request.Level, merkleDepth)
+	}
+	var tree merkleTree
+	var err error
+	a.run(func(n *Node) {
+		tree, err = n.merkleTree(request.Start, request.End)
+	})
+	if err != nil {
+		return err
+	}
+	for _, index := range request.Indexes {
+		if index < 0 || index >= len(tree[request.Level]) {
+			return fmt.Errorf("no node %d at level %d of a Merkle tree", index, request.Level)
+		}
+		*hashes = append(*hashes, tree[request.Level][index])
+	}
+	return nil
+}
+
+// MerkleItems returns this node's items in the requested leaves of a Merkle tree over a range
+func (a NodeActor) MerkleItems(request MerkleRequest, items *map[Key]Siblings) error {
+	var err error
+	a.run(func(n *Node) {
+		*items, err = n.merkleItems(request)
+	})
+	return err
+}
+
 // Dump delivers all info on a node
 func (a NodeActor) Dump(_ None, dumpReturn *DumpReturn) error {
 	a.run(func(n *Node) {
@@ -0,0 +1,110 @@
+package chord
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Listen on a free local port and never answer a call. A listener that connects holds up calls
+// once the RPC handshake is done, otherwise the handshake itself is held up.
+func blackhole(t *testing.T, connects bool) Address {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan None)
+	t.Cleanup(func() {
+		listener.Close()
+		<-done
+	})
+	go func() {
+		defer close(done)
+		conns := []net.Conn{}
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+			if connects {
+				http.ReadRequest(bufio.NewReader(conn))
+				io.WriteString(conn, "HTTP/1.0 200 Connected to Go RPC\n\n")
+			}
+		}
+	}()
+	return Address(listener.Addr().String())
+}
+
+// Fail the test unless an error is a timeout that came within a deadline
+func expectTimeout(t *testing.T, what string, start time.Time, deadline time.Duration, err error) {
+	t.Helper()
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("%s: got %v, expected a timeout", what, err)
+	}
+	// Leave room for a slow machine, but not for another deadline
+	if elapsed := time.Since(start); elapsed > deadline+deadline/2 {
+		t.Errorf("%s: gave up after %v, expected %v", what, elapsed, deadline)
+	}
+}
+
+func TestCallTimesOut(t *testing.T) {
+	for _, connects := range []bool{false, true} {
+		address := blackhole(t, connects)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		start := time.Now()
+		var success bool
+		err := call(ctx, address, "NodeActor.Ping", None{}, &success)
+		cancel()
+		expectTimeout(t, "one-off call", start, 200*time.Millisecond, err)
+
+		cfg := tcpConfig()
+		client, err := NewClient(cfg, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start = time.Now()
+		err = client.call(context.Background(), address, "NodeActor.Ping", None{}, &success)
+		expectTimeout(t, "client call", start, cfg.CallTimeout, err)
+		client.Close()
+	}
+}
+
+func TestLookupTimesOut(t *testing.T) {
+	address := blackhole(t, true)
+	cfg := tcpConfig()
+	// The whole lookup runs out before its first call does
+	cfg.CallTimeout = time.Second
+	cfg.LookupTimeout = 200 * time.Millisecond
+	client, err := NewClient(cfg, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	start := time.Now()
+	_, err = client.Lookup(context.Background(), "key")
+	expectTimeout(t, "lookup", start, cfg.LookupTimeout, err)
+}
+
+func TestDialRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := Address(listener.Addr().String())
+	listener.Close()
+	if _, err := dial(context.Background(), address); err == nil || strings.Contains(err.Error(), "timed out") {
+		t.Errorf("dialing a closed port: got %v, expected the connection to be refused", err)
+	}
+}
@@ -0,0 +1,138 @@
+package chord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A scan lists the keys stored on the ring in the order of their hashes. It starts at the node owning
+// the position after the cursor and walks the ring through successors, asking each node for the keys
+// in its own range. Reserved keys are never listed, except that the keys of a bucket are listed by Bucket.Keys. Keys written or moved between nodes while a scan is under way
+// may be missed or repeated.
+
+const defaultScanLimit = 100 // Keys in a page when no limit is given
+
+// ScanPage is one page of keys from a scan of the ring
+type ScanPage struct {
+	Keys   []Key  // The keys in the order of their hashes
+	Cursor string // Where the next page starts, empty once the whole ring has been scanned
+}
+
+// Scan lists up to limit keys, starting after the cursor of an earlier page or at the start of the ring for an empty cursor.
+// With a pattern only keys matching it are listed, using the syntax of path.Match.
+// A page can hold fewer than limit keys even when more follow, so keep scanning until the cursor is empty.
+func (c *Client) Scan(ctx context.Context, cursor string, pattern string, limit int) (ScanPage, error) {
+	return c.scan(ctx, cursor, "", pattern, limit)
+}
+
+// Scan the keys starting with a prefix, matching the rest of each key against the pattern
+func (c *Client) scan(ctx context.Context, cursor string, prefix string, pattern string, limit int) (ScanPage, error) {
+	if limit <= 0 {
+		limit = defaultScanLimit
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return ScanPage{}, fmt.Errorf("bad pattern %q: %v", pattern, err)
+	}
+	// Positions are exclusive, so start before the first hash
+	after := big.NewInt(-1)
+	if cursor != "" {
+		var ok bool
+		if after, ok = new(big.Int).SetString(cursor, 16); !ok || after.Sign() < 0 || after.Cmp(hashMod) >= 0 {
+			return ScanPage{}, fmt.Errorf("bad cursor %q", cursor)
+		}
+	}
+	last := new(big.Int).Sub(hashMod, big.NewInt(1))
+	page := ScanPage{Keys: []Key{}}
+	if after.Cmp(last) >= 0 {
+		return page, nil
+	}
+
+	err := c.walkRing(ctx, after, func(address Address, after, end *big.Int) (bool, error) {
+		var reply ScanReply
+		request := ScanRequest{after, end, prefix, pattern, limit - len(page.Keys)}
+		if err := c.call(ctx, address, "NodeActor.Scan", request, &reply); err != nil {
+			return false, fmt.Errorf("scanning %s: %v", address, err)
+		}
+		page.Keys = append(page.Keys, reply.Keys...)
+		if reply.More {
+			page.Cursor = cursorAt(reply.Keys[len(reply.Keys)-1].hashed())
+			return false, nil
+		}
+		if len(page.Keys) >= limit && end.Cmp(last) != 0 {
+			page.Cursor = cursorAt(end)
+			return false, nil
+		}
+		return true, nil
+	})
+	return page, err
+}
+
+// Walk the ring through successors from the node owning the position after a start, visiting each node with the range
+// (after, end] it owns until the visit says to stop or the end of the ring is reached. The ranges come from the walk
+// rather than from each node's predecessor, which a node can be without for a while, so no range is visited twice.
+func (c *Client) walkRing(ctx context.Context, after *big.Int, visit func(address Address, after, end *big.Int) (bool, error)) error {
+	last := new(big.Int).Sub(hashMod, big.NewInt(1))
+	address, err := c.lookupID(ctx, new(big.Int).Add(after, big.NewInt(1)))
+	if err != nil {
+		return fmt.Errorf("finding where to start: %v", err)
+	}
+	for {
+		// A node's range ends at its own position, except for the node past zero which also owns the end of the ring
+		end := address.hashed()
+		if end.Cmp(after) <= 0 {
+			end = last
+		}
+		if more, err := visit(address, after, end); err != nil || !more || end.Cmp(last) == 0 {
+			return err
+		}
+		after = end
+		var links NodeLink
+		if err := c.call(ctx, address, "NodeActor.GetNodeLinks", None{}, &links); err != nil {
+			return fmt.Errorf("finding the successor of %s: %v", address, err)
+		}
+		if len(links.Successors) == 0 {
+			return errors.New("node has no successor")
+		}
+		address = links.Successors[0]
+	}
+}
+
+// The cursor that continues a scan after a position on the ring
+func cursorAt(position *big.Int) string {
+	return fmt.Sprintf("%040x", position)
+}
+
+// The live keys of the node in a range that have the prefix and match the pattern, in the order of their hashes
+func (n *Node) scan(request ScanRequest) (ScanReply, error) {
+	items, err := n.Data.Range(request.Start, request.End)
+	if err != nil {
+		return ScanReply{}, err
+	}
+	now := time.Now()
+	keys := []Key{}
+	for key, siblings := range items {
+		// Reserved keys are only listed by a scan of a bucket, whose prefix is reserved as well
+		if (key.reserved() && !Key(request.Prefix).reserved()) || len(siblings.live(now)) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(string(key), request.Prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(string(key), request.Prefix)
+		if matched, _ := path.Match(request.Pattern, name); request.Pattern != "" && !matched {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].hashed().Cmp(keys[j].hashed()) < 0 })
+	if request.Limit > 0 && len(keys) > request.Limit {
+		return ScanReply{keys[:request.Limit], true}, nil
+	}
+	return ScanReply{keys, false}, nil
+}
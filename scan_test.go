@@ -0,0 +1,96 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// Every key of a scan, following the cursor page by page
+func scanAll(t *testing.T, client *Client, pattern string, limit int) []Key {
+	t.Helper()
+	keys := []Key{}
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 1000 {
+			t.Fatal("scan did not end")
+		}
+		page, err := client.Scan(context.Background(), cursor, pattern, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Keys) > limit {
+			t.Errorf("page of %d keys with a limit of %d", len(page.Keys), limit)
+		}
+		keys = append(keys, page.Keys...)
+		if cursor = page.Cursor; cursor == "" {
+			return keys
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 4, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	items := map[Key]string{}
+	for i := 0; i < 40; i++ {
+		items[Key(fmt.Sprint("user/", i))] = fmt.Sprint(i)
+		items[Key(fmt.Sprint("order/", i))] = fmt.Sprint(i)
+	}
+	if err := client.MultiPut(ctx, items, Consistency{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Delete(ctx, "user/0"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, limit := range []int{1, 7, 1000} {
+		keys := scanAll(t, client, "", limit)
+		if len(keys) != len(items)-1 {
+			t.Errorf("limit %d: scanned %d keys, expected %d", limit, len(keys), len(items)-1)
+		}
+		listed := map[Key]bool{}
+		for i, key := range keys {
+			if listed[key] {
+				t.Errorf("limit %d: %s listed twice", limit, string(key))
+			}
+			listed[key] = true
+			if i > 0 && keys[i-1].hashed().Cmp(key.hashed()) >= 0 {
+				t.Errorf("limit %d: %s listed after %s", limit, string(key), string(keys[i-1]))
+			}
+		}
+		if listed["user/0"] {
+			t.Errorf("limit %d: deleted key listed", limit)
+		}
+	}
+
+	users := scanAll(t, client, "user/*", 10)
+	if len(users) != 39 {
+		t.Errorf("scanned %d keys matching user/*, expected 39", len(users))
+	}
+	for _, key := range users {
+		if !strings.HasPrefix(string(key), "user/") {
+			t.Errorf("%s matched user/*", string(key))
+		}
+	}
+}
+
+func TestScanRejectsBadArguments(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 1, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if _, err := client.Scan(ctx, "not hex", "", 10); err == nil {
+		t.Error("scan with a bad cursor succeeded")
+	}
+	if _, err := client.Scan(ctx, "", "[", 10); err == nil {
+		t.Error("scan with a bad pattern succeeded")
+	}
+	if page, err := client.Scan(ctx, cursorAt(new(big.Int).Sub(hashMod, big.NewInt(1))), "", 10); err != nil || len(page.Keys) != 0 || page.Cursor != "" {
+		t.Errorf("scan from the end of the ring: got %v, %v", page, err)
+	}
+}
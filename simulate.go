@@ -0,0 +1,152 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+type (
+	// LinkFaults describes the faults injected into messages travelling one way between two addresses of a MemoryNetwork
+	LinkFaults struct {
+		Latency     time.Duration // Delay added to every message
+		Loss        float64       // Probability in [0, 1] that a message is dropped
+		Partitioned bool          // Whether every message is dropped
+	}
+
+	// A one way link between two addresses
+	link struct {
+		from Address
+		to   Address
+	}
+)
+
+// Seed makes the random message loss of the network repeatable
+func (m *MemoryNetwork) Seed(seed int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rng = rand.New(rand.NewSource(seed))
+}
+
+// SetDefaultFaults sets the faults for every link that has none of its own
+func (m *MemoryNetwork) SetDefaultFaults(faults LinkFaults) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.defaultFaults = faults
+}
+
+// SetLinkFaults sets the faults for messages sent from one address to another.
+// Messages in the other direction are not affected.
+func (m *MemoryNetwork) SetLinkFaults(from, to Address, faults LinkFaults) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.links[link{from, to}] = faults
+}
+
+// Partition drops every message sent from one address to another.
+// Messages in the other direction still get through, so calls made the other way execute but their replies are lost.
+func (m *MemoryNetwork) Partition(from, to Address) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	faults, exists := m.links[link{from, to}]
+	if !exists {
+		faults = m.defaultFaults
+	}
+	faults.Partitioned = true
+	m.links[link{from, to}] = faults
+}
+
+// Heal removes all link faults and partitions. Crashed addresses stay crashed.
+func (m *MemoryNetwork) Heal() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.links = make(map[link]LinkFaults)
+	m.defaultFaults = LinkFaults{}
+}
+
+// Crash silently drops every message to or from an address, as if the machine had died
+func (m *MemoryNetwork) Crash(address Address) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.crashed[address] = true
+}
+
+// Recover lets messages to and from a crashed address through again
+func (m *MemoryNetwork) Recover(address Address) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.crashed, address)
+}
+
+// Apply the faults of a link to one message, blocking for any latency.
+// Dropped messages never arrive, so the sender waits until the context is done.
+func (m *MemoryNetwork) deliver(ctx context.Context, from, to Address, method string) error {
+	m.mu.Lock()
+	faults, exists := m.links[link{from, to}]
+	if !exists {
+		faults = m.defaultFaults
+	}
+	dropped := m.crashed[from] || m.crashed[to] || faults.Partitioned ||
+		(faults.Loss > 0 && m.rng.Float64() < faults.Loss)
+	m.mu.Unlock()
+
+	if dropped {
+		<-ctx.Done()
+		return contextError(ctx, fmt.Sprintf("calling %s", method))
+	}
+	if faults.Latency > 0 {
+		timer := time.NewTimer(faults.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return contextError(ctx, fmt.Sprintf("calling %s", method))
+		}
+	}
+	return nil
+}
+
+// CheckRing returns an error describing the first node whose successor or predecessor
+// does not match its neighbour in a single ring made up of exactly the given nodes.
+// It is meant for tests and simulations waiting for a ring to repair itself.
+func CheckRing(nodes []*Node) error {
+	ordered := append([]*Node{}, nodes...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Hash.Cmp(ordered[j].Hash) < 0
+	})
+	for i, n := range ordered {
+		successor := ordered[(i+1)%len(ordered)]
+		predecessor := ordered[(i+len(ordered)-1)%len(ordered)]
+		// The links belong to the node's actor
+		var links NodeLink
+		n.actor.run(func(n *Node) {
+			links = NodeLink{n.Predecessor, append([]Address{}, n.Successors...)}
+		})
+		if len(links.Successors) == 0 || links.Successors[0] != successor.Address {
+			return fmt.Errorf("%s: successor is %v, expected %s", n.Address, links.Successors, successor.Address)
+		}
+		if links.Predecessor != predecessor.Address {
+			return fmt.Errorf("%s: predecessor is %s, expected %s", n.Address, links.Predecessor, predecessor.Address)
+		}
+	}
+	return nil
+}
+
+// WaitForRing polls CheckRing until it passes or the context is done
+func WaitForRing(ctx context.Context, nodes []*Node) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		err := CheckRing(nodes)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("ring not repaired: %v", err)
+		}
+	}
+}
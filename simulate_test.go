@@ -0,0 +1,102 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRingLookups(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 8, nil)
+	client := testClient(t, network, nodes)
+	for i := 0; i < 50; i++ {
+		key := Key(fmt.Sprint("key", i))
+		owner, err := client.Lookup(context.Background(), key)
+		if err != nil {
+			t.Fatalf("looking up %s: %v", string(key), err)
+		}
+		if expected := ownerOf(nodes, key)[0].Address; owner != expected {
+			t.Errorf("%s: owner is %s, expected %s", string(key), owner, expected)
+		}
+	}
+}
+
+func TestRingRepairsAfterCrash(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := byHash(testRing(t, network, 6, nil))
+	network.Crash(nodes[2].Address)
+	network.Crash(nodes[3].Address)
+	live := append(append([]*Node{}, nodes[:2]...), nodes[4:]...)
+	waitForRing(t, live)
+
+	network.Recover(nodes[2].Address)
+	network.Recover(nodes[3].Address)
+	waitForRing(t, nodes)
+}
+
+func TestPartitionIsOneWay(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 2, nil)
+	a, b := nodes[0].Address, nodes[1].Address
+	ping := func(from, to Address) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		var success bool
+		return network.Transport(from).Call(ctx, to, "NodeActor.Ping", None{}, &success)
+	}
+
+	network.Partition(a, b)
+	if err := ping(a, b); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("request across the partition: got %v, expected a timeout", err)
+	}
+	// The reply from b to a is dropped as well
+	if err := ping(b, a); err == nil {
+		t.Error("reply across the partition got through")
+	}
+	network.Heal()
+	if err := ping(a, b); err != nil {
+		t.Errorf("after healing: %v", err)
+	}
+}
+
+func TestLinkFaults(t *testing.T) {
+	network := NewMemoryNetwork()
+	network.Seed(1)
+	nodes := testRing(t, network, 2, nil)
+	a, b := nodes[0].Address, nodes[1].Address
+	ping := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		var success bool
+		return network.Transport(a).Call(ctx, b, "NodeActor.Ping", None{}, &success)
+	}
+
+	network.SetLinkFaults(a, b, LinkFaults{Latency: 50 * time.Millisecond})
+	start := time.Now()
+	if err := ping(); err != nil {
+		t.Fatalf("slow link: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("call took %v over a link with 50ms latency", elapsed)
+	}
+
+	network.SetLinkFaults(a, b, LinkFaults{Loss: 1})
+	if err := ping(); err == nil {
+		t.Error("call got through a link that loses every message")
+	}
+}
+
+func TestCheckRing(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	if err := CheckRing(nodes); err != nil {
+		t.Errorf("formed ring: %v", err)
+	}
+	// A ring missing one of its members is not the ring of the others
+	if err := CheckRing(nodes[1:]); err == nil {
+		t.Error("CheckRing passed without a member of the ring")
+	}
+}
@@ -0,0 +1,83 @@
+package chord
+
+import (
+	"math/big"
+)
+
+type (
+	// Storage holds the versions of the items stored at a node, both the ones it owns and its replicas.
+	// The node only calls it from its actor, one call at a time, so engines need no locking of their own.
+	// Maps and siblings returned by an engine belong to the caller.
+	Storage interface {
+		Get(key Key) (Siblings, error)                       // The versions of a key, nil if it is not stored
+		Put(key Key, siblings Siblings) error                // Replace the versions of a key
+		Delete(key Key) error                                // Remove a key, doing nothing if it is not stored
+		Range(start, end *big.Int) (map[Key]Siblings, error) // The items whose key hashes are in (start, end] of the ring
+		Count() (int, error)                                 // How many keys are stored
+		Snapshot() (map[Key]Siblings, error)                 // A copy of every item
+		Close() error                                        // Release the engine's resources when the node stops
+	}
+
+	// Storage engine that keeps every item in a map
+	memoryStorage struct {
+		items map[Key]Siblings
+	}
+)
+
+// NewMemoryStorage creates an empty storage engine that keeps items in memory. They are lost when the node stops.
+func NewMemoryStorage() Storage {
+	return &memoryStorage{items: make(map[Key]Siblings)}
+}
+
+func (s *memoryStorage) Get(key Key) (Siblings, error) {
+	return s.items[key], nil
+}
+
+func (s *memoryStorage) Put(key Key, siblings Siblings) error {
+	s.items[key] = siblings
+	return nil
+}
+
+func (s *memoryStorage) Delete(key Key) error {
+	delete(s.items, key)
+	return nil
+}
+
+func (s *memoryStorage) Range(start, end *big.Int) (map[Key]Siblings, error) {
+	items := make(map[Key]Siblings)
+	for key, siblings := range s.items {
+		if between(start, key.hashed(), end, true) {
+			items[key] = siblings
+		}
+	}
+	return items, nil
+}
+
+func (s *memoryStorage) Count() (int, error) {
+	return len(s.items), nil
+}
+
+func (s *memoryStorage) Snapshot() (map[Key]Siblings, error) {
+	items := make(map[Key]Siblings, len(s.items))
+	for key, siblings := range s.items {
+		items[key] = siblings
+	}
+	return items, nil
+}
+
+func (s *memoryStorage) Close() error {
+	return nil
+}
+
+// Merge versions of a key into the ones stored, dropping any that are superseded
+func (n *Node) merge(key Key, siblings ...Item) error {
+	existing, err := n.Data.Get(key)
+	if err != nil {
+		return err
+	}
+	merged := existing.merge(siblings...)
+	if len(merged) == 0 {
+		return nil
+	}
+	return n.Data.Put(key, merged)
+}
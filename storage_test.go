@@ -0,0 +1,101 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// Check the behaviour every storage engine shares
+func testEngine(t *testing.T, s Storage) {
+	t.Helper()
+	item := Item{Value: []byte("v"), Version: Version{"n1", 1, VectorClock{}}}
+	for i := 0; i < 20; i++ {
+		if err := s.Put(Key(fmt.Sprint("key", i)), Siblings{item}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if siblings, err := s.Get("key3"); err != nil || !siblings.equal(Siblings{item}) {
+		t.Errorf("get: got %v, %v", siblings, err)
+	}
+	if siblings, err := s.Get("missing"); err != nil || siblings != nil {
+		t.Errorf("get of a missing key: got %v, %v", siblings, err)
+	}
+	if err := s.Delete("key3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("missing"); err != nil {
+		t.Errorf("delete of a missing key: %v", err)
+	}
+	if count, err := s.Count(); err != nil || count != 19 {
+		t.Errorf("count: got %d, %v", count, err)
+	}
+
+	// The ranges either side of a point cover every key once, including the one that wraps round zero
+	point := Key("key7").hashed()
+	before, err := s.Range(point, point)
+	if err != nil || len(before) != 19 {
+		t.Errorf("range of the whole ring: got %d keys, %v", len(before), err)
+	}
+	half := new(big.Int).Add(point, new(big.Int).Rsh(hashMod, 1))
+	half.Mod(half, hashMod)
+	first, _ := s.Range(point, half)
+	second, _ := s.Range(half, point)
+	if len(first)+len(second) != 19 {
+		t.Errorf("two halves of the ring hold %d and %d keys", len(first), len(second))
+	}
+	if _, ok := second["key7"]; !ok {
+		t.Error("key7 is not in the range it ends")
+	}
+
+	snapshot, err := s.Snapshot()
+	if err != nil || len(snapshot) != 19 {
+		t.Fatalf("snapshot: got %d keys, %v", len(snapshot), err)
+	}
+	delete(snapshot, "key0")
+	if siblings, _ := s.Get("key0"); siblings == nil {
+		t.Error("changing a snapshot changed the engine")
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	testEngine(t, NewMemoryStorage())
+}
+
+func TestConfiguredStorage(t *testing.T) {
+	network := NewMemoryNetwork()
+	engines := map[Address]Storage{}
+	nodes := testRing(t, network, 3, func(cfg *Config) {
+		cfg.Storage = NewMemoryStorage()
+		engines[cfg.Transport.(*memoryTransport).address] = cfg.Storage
+	})
+	client := testClient(t, network, nodes)
+	if err := client.PutWith(context.Background(), "key", "value", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range nodes {
+		if siblings, _ := engines[n.Address].Get("key"); len(siblings) != 1 || string(siblings[0].Value) != "value" {
+			t.Errorf("engine of %s holds %v", n.Address, siblings)
+		}
+	}
+}
+
+func TestNodeMerge(t *testing.T) {
+	network := NewMemoryNetwork()
+	n := testRing(t, network, 1, nil)[0]
+	first := Item{Value: []byte("a"), Version: Version{"n1", 1, VectorClock{}}}
+	second := Item{Value: []byte("b"), Version: Version{"n1", 2, VectorClock{"n1": 1}}}
+	var err error
+	n.actor.run(func(n *Node) {
+		if err = n.merge("key", second); err == nil {
+			err = n.merge("key", first)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if siblings := stored(n, "key"); len(siblings) != 1 || string(siblings[0].Value) != "b" {
+		t.Errorf("after merging an older version: holds %v", siblings)
+	}
+}
@@ -0,0 +1,74 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/rpc"
+)
+
+type (
+	// Transport carries NodeActor method calls between nodes.
+	// Each node needs a transport of its own. Clients may share one, including a node's, since they only close the transports they create.
+	Transport interface {
+		// Listen claims an address for the local node and returns the address other nodes should use to reach it
+		Listen() (Address, error)
+		// Serve starts handling calls to the claimed address with the methods of the actor
+		Serve(actor NodeActor) error
+		// Call invokes a NodeActor method (e.g. "NodeActor.Get") on the node at an address
+		Call(ctx context.Context, address Address, method string, request interface{}, reply interface{}) error
+		// Close stops serving and releases all connections
+		Close() error
+	}
+
+	// The default transport using net/rpc over HTTP
+	rpcTransport struct {
+		config   Config
+		pool     *pool
+		listener net.Listener
+	}
+)
+
+// NewRPCTransport creates a transport using net/rpc over HTTP.
+// It listens on the BindAddress and Port of the config and advertises the AdvertiseAddress.
+func NewRPCTransport(cfg Config) Transport {
+	return &rpcTransport{
+		config: cfg.withDefaults(),
+		pool:   newPool(),
+	}
+}
+
+func (t *rpcTransport) Listen() (Address, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(t.config.BindAddress, fmt.Sprint(t.config.Port)))
+	if err != nil {
+		return "", fmt.Errorf("listen error: %v", err)
+	}
+	t.listener = newTrackingListener(listener)
+	// Use the port actually chosen in case it was picked by the system
+	port := listener.Addr().(*net.TCPAddr).Port
+	return Address(net.JoinHostPort(t.config.AdvertiseAddress, fmt.Sprint(port))), nil
+}
+
+func (t *rpcTransport) Serve(actor NodeActor) error {
+	server := rpc.NewServer()
+	if err := server.Register(actor); err != nil {
+		return fmt.Errorf("registering actor: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(rpc.DefaultRPCPath, server)
+	go http.Serve(t.listener, mux)
+	return nil
+}
+
+func (t *rpcTransport) Call(ctx context.Context, address Address, method string, request interface{}, reply interface{}) error {
+	return t.pool.call(ctx, address, method, request, reply)
+}
+
+func (t *rpcTransport) Close() error {
+	t.pool.close()
+	if t.listener != nil {
+		return t.listener.Close()
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// Start a ring of count nodes on the RPC transport and wait for it to form, stopping the nodes when the test ends
+func tcpRing(t *testing.T, count int) []*Node {
+	t.Helper()
+	nodes := []*Node{tcpNode(t)}
+	for i := 1; i < count; i++ {
+		n, err := Join(tcpConfig(), nodes[0].Address)
+		if err != nil {
+			t.Fatalf("starting node %d: %v", i, err)
+		}
+		t.Cleanup(func() { n.stopNode() })
+		nodes = append(nodes, n)
+	}
+	waitForRing(t, nodes)
+	return nodes
+}
+
+func TestRingOverRPC(t *testing.T) {
+	nodes := tcpRing(t, 3)
+	seeds := []Address{}
+	for _, n := range nodes {
+		seeds = append(seeds, n.Address)
+	}
+	client, err := NewClient(tcpConfig(), seeds...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		key := Key(fmt.Sprint("key", i))
+		if err := client.PutWith(ctx, key, fmt.Sprint(i), Consistency{W: 3}); err != nil {
+			t.Fatalf("putting %s: %v", string(key), err)
+		}
+		if owner, err := client.Lookup(ctx, key); err != nil || owner != ownerOf(nodes, key)[0].Address {
+			t.Errorf("%s: owner is %s, %v", string(key), owner, err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		key := Key(fmt.Sprint("key", i))
+		if value, err := client.GetWith(ctx, key, Consistency{R: 3}); err != nil || value != fmt.Sprint(i) {
+			t.Errorf("%s: got %q, %v", string(key), value, err)
+		}
+	}
+
+	// A node leaving over the transport hands its keys to the rest of the ring
+	if err := nodes[1].Leave(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitForRing(t, []*Node{nodes[0], nodes[2]})
+	for i := 0; i < 20; i++ {
+		key := Key(fmt.Sprint("key", i))
+		if value, err := client.Get(ctx, key); err != nil || value != fmt.Sprint(i) {
+			t.Errorf("%s after a leave: got %q, %v", string(key), value, err)
+		}
+	}
+}
+
+func TestClientSharesNodeTransport(t *testing.T) {
+	n := tcpNode(t)
+	cfg := tcpConfig()
+	cfg.Transport = n.transport
+	shared, err := NewClient(cfg, n.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shared.Put(context.Background(), "key", "v"); err != nil {
+		t.Fatal(err)
+	}
+	// Closing a client leaves a transport it was given open, so the node keeps serving
+	shared.Close()
+	other, err := NewClient(tcpConfig(), n.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if value, err := other.Get(context.Background(), "key"); err != nil || value != "v" {
+		t.Errorf("after closing a client sharing the node's transport: got %q, %v", value, err)
+	}
+}
@@ -0,0 +1,75 @@
+package chord
+
+import (
+	"context"
+	"time"
+)
+
+// Items put with a TTL carry the time they expire, set once by the owner, so every copy of an item
+// expires at the same moment no matter how it was transferred. Expired items are hidden from reads
+// straight away and turned into tombstones by a sweeper running with the other maintenance tasks.
+// The tombstone keeps the item's version and is deleted at the moment the item expired, so every
+// replica makes the same one, and a later write counts past the expired one instead of reusing its version.
+// The sweeper also drops tombstones once Config.TombstoneTTL has passed since the delete. By then
+// anti-entropy has copied them to every replica it could reach, while a replica cut off for longer
+// than that can bring a deleted value back.
+
+// Whether an item has expired
+func (i Item) expired(now time.Time) bool {
+	return !i.Expires.IsZero() && !now.Before(i.Expires)
+}
+
+// The siblings that have not expired or been deleted, nil if none are left
+func (s Siblings) live(now time.Time) Siblings {
+	var live Siblings
+	for _, item := range s {
+		if !item.expired(now) && !item.deleted() {
+			live = append(live, item)
+		}
+	}
+	return live
+}
+
+// Turn expired items into tombstones and remove old tombstones from storage
+func (n *Node) expire(ctx context.Context) error {
+	var err error
+	swept := 0
+	n.actor.run(func(n *Node) {
+		var items map[Key]Siblings
+		if items, err = n.Data.Snapshot(); err != nil {
+			return
+		}
+		now := time.Now()
+		for key, siblings := range items {
+			var kept Siblings
+			changed := false
+			for _, item := range siblings {
+				if item.expired(now) && !item.deleted() {
+					item = Item{Version: item.Version, Deleted: item.Expires}
+					changed = true
+				}
+				if item.deleted() && now.Sub(item.Deleted) >= n.config.TombstoneTTL {
+					changed = true
+					continue
+				}
+				kept = append(kept, item)
+			}
+			if !changed {
+				continue
+			}
+			if len(kept) == 0 {
+				err = n.Data.Delete(key)
+			} else {
+				err = n.Data.Put(key, kept)
+			}
+			if err != nil {
+				return
+			}
+			swept++
+		}
+	})
+	if swept > 0 {
+		n.logger.Printf("expire: swept expired versions and old tombstones of %d keys", swept)
+	}
+	return err
+}
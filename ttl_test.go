@@ -0,0 +1,174 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExpiredItemsBecomeTombstones(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.PutTTL(ctx, "key", "short", 100*time.Millisecond, Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := client.Get(ctx, "key"); err != nil || value != "short" {
+		t.Fatalf("before expiring: got %q, %v", value, err)
+	}
+	written := stored(nodes[0], "key")[0]
+	time.Sleep(100 * time.Millisecond)
+	if value, err := client.Get(ctx, "key"); err != ErrNoSuchKey {
+		t.Errorf("after expiring: got %q, %v", value, err)
+	}
+
+	for _, n := range nodes {
+		n.expire(ctx)
+	}
+	tombstone := Item{Version: written.Version, Deleted: written.Expires}
+	for _, n := range nodes {
+		if siblings := stored(n, "key"); len(siblings) != 1 || !siblings[0].identical(tombstone) {
+			t.Errorf("%s holds %v after the sweep, expected %v", n.Address, siblings, tombstone)
+		}
+	}
+}
+
+func TestWriteAfterExpiry(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.PutTTL(ctx, "s", "old", 100*time.Millisecond, Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	// Only the owner sweeps, so the replicas still hold the expired value when the new one arrives
+	owner := ownerOf(nodes, "s")[0]
+	owner.expire(ctx)
+	if err := client.PutWith(ctx, "s", "new", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	written := stored(owner, "s")
+	if len(written) != 1 || string(written[0].Value) != "new" || written[0].Version.Counter != 2 {
+		t.Fatalf("owner holds %v", written)
+	}
+	for _, n := range nodes {
+		n.expire(ctx)
+		if copied := stored(n, "s"); !copied.equal(written) {
+			t.Errorf("%s holds %v, owner %v", n.Address, copied, written)
+		}
+	}
+	if value, err := client.GetWith(ctx, "s", Consistency{R: 3}); err != nil || value != "new" {
+		t.Errorf("get after the new write: got %q, %v", value, err)
+	}
+}
+
+func TestExpiredTombstonesAreCollected(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 1, func(cfg *Config) {
+		cfg.TombstoneTTL = 100 * time.Millisecond
+	})
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.PutTTL(ctx, "key", "short", 100*time.Millisecond, Consistency{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	nodes[0].expire(ctx)
+	if siblings := stored(nodes[0], "key"); siblings != nil {
+		t.Errorf("an item that expired longer ago than the tombstone TTL left %v", siblings)
+	}
+}
+
+func TestNegativeTTL(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 1, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.PutTTL(ctx, "key", "v", -time.Second, Consistency{}); err == nil {
+		t.Error("put with a negative TTL succeeded")
+	}
+	if siblings := stored(nodes[0], "key"); siblings != nil {
+		t.Errorf("put with a negative TTL stored %v", siblings)
+	}
+	hint := Hint{Owner: "elsewhere", Request: PutRequest{KeyValue: KeyValue{Key: "key", Value: []byte("v")}, TTL: -time.Second}}
+	if err := client.call(ctx, nodes[0].Address, "NodeActor.Hint", hint, &None{}); err == nil {
+		t.Error("hint with a negative TTL was taken")
+	}
+}
+
+func TestHintedTTL(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	ctx := context.Background()
+	owner := ownerOf(nodes, "session")[0]
+	holder := ownerOf(nodes, "session")[1]
+
+	// The TTL ran out while the holder was finding the owner
+	hint := Hint{
+		Owner:    owner.Address,
+		Request:  PutRequest{KeyValue: KeyValue{Key: "session", Value: []byte("v")}, TTL: 50 * time.Millisecond},
+		Received: time.Now().Add(-60 * time.Millisecond),
+	}
+	if err := holder.deliverHint(ctx, &hint); err != errHintExpired {
+		t.Errorf("delivering an expired hint: %v", err)
+	}
+	if siblings := stored(owner, "session"); siblings != nil {
+		t.Errorf("an expired hint stored %v", siblings)
+	}
+
+	// A delivered hint expires when it would have if the owner had taken the put
+	hint.Request.TTL = time.Hour
+	if err := holder.deliverHint(ctx, &hint); err != nil {
+		t.Fatal(err)
+	}
+	siblings := stored(owner, "session")
+	if len(siblings) != 1 {
+		t.Fatalf("owner holds %v after the delivery", siblings)
+	}
+	// The put reaches the owner a moment after the TTL left is worked out
+	if expires := hint.Received.Add(time.Hour); siblings[0].Expires.Sub(expires) > time.Second || expires.Sub(siblings[0].Expires) > 0 {
+		t.Errorf("delivered item expires at %v, expected %v", siblings[0].Expires, expires)
+	}
+}
+
+func TestTTLSurvivesTransfers(t *testing.T) {
+	network := NewMemoryNetwork()
+	// With one copy of each item the key only survives through the transfers
+	oneCopy := func(cfg *Config) { cfg.Replicas = 1 }
+	nodes := testRing(t, network, 2, oneCopy)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	joining := &Node{Address: "node2", Hash: Address("node2").hashed()}
+	var key Key
+	for i := 0; ownerOf(append(nodes, joining), key)[0] != joining; i++ {
+		key = Key(fmt.Sprint("key", i))
+	}
+	if err := client.PutTTL(ctx, key, "v", time.Hour, Consistency{}); err != nil {
+		t.Fatal(err)
+	}
+	written := stored(ownerOf(nodes, key)[0], key)[0]
+
+	// Taken over by a node that joins
+	cfg := testConfig(network, joining.Address)
+	oneCopy(&cfg)
+	joined, err := Join(cfg, nodes[0].Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { joined.stopNode() })
+	if siblings := stored(joined, key); len(siblings) != 1 || !siblings[0].Expires.Equal(written.Expires) {
+		t.Errorf("joined node holds %v, expected it to expire at %v", siblings, written.Expires)
+	}
+
+	// Handed back when it leaves
+	if err := joined.Leave(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if siblings := stored(ownerOf(nodes, key)[0], key); len(siblings) != 1 || !siblings[0].Expires.Equal(written.Expires) {
+		t.Errorf("after the leave the owner holds %v, expected it to expire at %v", siblings, written.Expires)
+	}
+}
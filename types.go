@@ -1,8 +1,13 @@
-package main
+package chord
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"math/big"
+	"net"
+	"net/http"
+	"time"
 )
 
 type (
@@ -18,7 +23,22 @@ type (
 		Successors  []Address
 		Predecessor Address
 		Fingers     [numFingerEntries]Address // The finger table pointing to addresses farther down the ring (increasing by powers of 2)
-		Data        map[Key]string            // The data items stored at this node
+		Data        Storage                   // The versions of the data items stored at this node
+
+		config          Config             // The settings the node was started with
+		logger          *log.Logger        // Where log messages are written
+		actor           NodeActor          // Runs operations on the node one at a time
+		transport       Transport          // How calls reach other nodes
+		jsonListener    net.Listener       // The JSON-RPC listener, if enabled
+		httpServer      *http.Server       // The REST gateway, if enabled
+		client          *Client            // Used to perform ring operations starting at this node
+		ctx             context.Context    // Canceled when the node leaves the ring
+		cancel          context.CancelFunc // Cancels ctx
+		nextFinger      int                // The next entry in the finger table to fix
+		hints           []Hint             // Puts held for owners that could not be reached
+		savedSuccessors []Address          // The successors last saved to the data directory
+		orphans         map[Key]time.Time  // The chunks no manifest listed when they were last collected, and since when
+		watches         watchHub           // Wakes the polls watching each key
 	}
 
 	// Hashable can be hashed and implements fmt.Stringer
@@ -39,7 +59,80 @@ type (
 	// KeyValue is a data item to be stored
 	KeyValue struct {
 		Key   Key
-		Value string
+		Value []byte
+	}
+
+	// PutRequest asks the owner of a key to store a value
+	PutRequest struct {
+		KeyValue
+		Seen        VectorClock   // The versions the writer has read and replaces, nil to replace every version
+		TTL         time.Duration // How long the value lives, zero to keep it until it is replaced
+		Condition   Condition     // What must be stored for the put to go ahead
+		Manifest    bool          // Whether the value lists the chunks of a value stored with PutLarge
+		Consistency Consistency
+	}
+
+	// GetRequest asks the owner of a key for its value
+	GetRequest struct {
+		Key         Key
+		Consistency Consistency
+	}
+
+	// DeleteRequest asks the owner of a key to remove it
+	DeleteRequest struct {
+		Key         Key
+		Condition   Condition // What must be stored for the delete to go ahead
+		Consistency Consistency
+	}
+
+	// MultiPutRequest asks a node to store values for many of the keys it owns
+	MultiPutRequest struct {
+		Items       map[Key][]byte
+		Consistency Consistency
+	}
+
+	// MultiKeyRequest asks a node to read or delete many of the keys it owns
+	MultiKeyRequest struct {
+		Keys        []Key
+		Consistency Consistency
+	}
+
+	// ScanRequest asks a node for the keys it stores in the range (Start, End] of the ring
+	ScanRequest struct {
+		Start   *big.Int
+		End     *big.Int
+		Prefix  string // Only keys starting with this, which is removed before matching the pattern
+		Pattern string // Only keys matching this glob, every key if empty
+		Limit   int    // The most keys to return, no limit if zero
+	}
+
+	// ScanReply holds the keys found by a scan of a node
+	ScanReply struct {
+		Keys []Key
+		More bool // Whether the limit cut the keys short
+	}
+
+	// BucketStatsRequest asks a node for the stats of the buckets it stores keys of in the range (Start, End] of the ring
+	BucketStatsRequest struct {
+		Start  *big.Int
+		End    *big.Int
+		Bucket string // Only this bucket, every bucket if empty
+	}
+
+	// WatchRequest asks the owner of a key to reply once the key differs from what the watcher has seen
+	WatchRequest struct {
+		Key    Key
+		Seen   VectorClock   // The versions the watcher has seen
+		Exists bool          // Whether the key was stored when the watcher last saw it
+		Wait   time.Duration // How long to wait for a change
+	}
+
+	// WatchReply tells a watcher whether a key changed while it waited
+	WatchReply struct {
+		Changed  bool
+		Deleted  bool
+		Moved    bool     // Whether the node no longer owns the key, so the watcher has to find the owner again
+		Siblings Siblings // The live versions if the key was put
 	}
 
 	// AddressResult represents a return address and if that address is the desired address
@@ -54,6 +147,12 @@ type (
 		Successors  []Address
 	}
 
+	// A finger table entry
+	fingerEntry struct {
+		Entry   int     `json:"entry"`
+		Address Address `json:"address"`
+	}
+
 	// DumpReturn contains the dump info and the successor address
 	DumpReturn struct {
 		Dump      string // The string containing all the dump info
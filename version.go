@@ -0,0 +1,209 @@
+package chord
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Every write of a key is tagged with a Version: the node that accepted it, a counter of that node's writes to the key,
+// and a vector clock of the earlier writes the writer had seen. A version supersedes every write it had seen.
+// Versions that have not seen each other were written concurrently and are kept side by side as siblings
+// until a later write that has seen all of them replaces them.
+//
+// Deleting a key writes a tombstone: a version with no value that replaces the versions the delete saw.
+// It is stored and copied to the replicas like any other version, so a delete a replica missed is not undone,
+// and a node keeps counting its writes to a deleted key instead of reusing the versions of earlier ones.
+
+type (
+	// VectorClock maps each node that accepted writes of a key to the latest of those writes that has been seen
+	VectorClock map[Address]uint64
+
+	// Version identifies one write of a key
+	Version struct {
+		Node    Address     // The node that accepted the write
+		Counter uint64      // That node's count of writes to the key
+		Seen    VectorClock // The writes of the key the writer had already seen
+	}
+
+	// Item is one version of the value of a key
+	Item struct {
+		Value    []byte
+		Version  Version
+		Expires  time.Time // When the item disappears, zero if it never does
+		Deleted  time.Time // When the key was deleted if the item is a tombstone, zero for a value
+		Manifest bool      // Whether the value lists the chunks of a value stored with PutLarge
+	}
+
+	// Siblings are the versions of a key that were written concurrently, none of which supersedes another
+	Siblings []Item
+
+	// ConflictError is returned when a key has concurrent versions and a single value was asked for.
+	// Resolve the conflict by reading the siblings and writing a value with their context.
+	ConflictError struct {
+		Key      Key
+		Siblings Siblings
+	}
+)
+
+// Whether a clock includes a write
+func (v VectorClock) includes(node Address, counter uint64) bool {
+	return v[node] >= counter
+}
+
+// Merge another clock into a copy of this one, keeping the latest counter of every node
+func (v VectorClock) merge(other VectorClock) VectorClock {
+	merged := make(VectorClock, len(v)+len(other))
+	for node, counter := range v {
+		merged[node] = counter
+	}
+	for node, counter := range other {
+		if counter > merged[node] {
+			merged[node] = counter
+		}
+	}
+	return merged
+}
+
+func (v VectorClock) String() string {
+	entries := []string{}
+	for node, counter := range v {
+		entries = append(entries, fmt.Sprintf("%s=%d", string(node), counter))
+	}
+	sort.Strings(entries)
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// Clock includes the write itself and every write it had seen
+func (v Version) Clock() VectorClock {
+	return v.Seen.merge(VectorClock{v.Node: v.Counter})
+}
+
+// Whether this write had seen another, different write
+func (v Version) supersedes(other Version) bool {
+	return v.Seen.includes(other.Node, other.Counter) && !v.same(other)
+}
+
+// Whether two versions are the same write
+func (v Version) same(other Version) bool {
+	return v.Node == other.Node && v.Counter == other.Counter
+}
+
+// Whether an item is a tombstone left by a delete
+func (i Item) deleted() bool {
+	return !i.Deleted.IsZero()
+}
+
+// Whether two items are copies of the same write with the same contents
+func (i Item) identical(other Item) bool {
+	return i.Version.same(other.Version) && bytes.Equal(i.Value, other.Value) &&
+		i.Expires.Equal(other.Expires) && i.Deleted.Equal(other.Deleted) && i.Manifest == other.Manifest
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%s#%d", string(v.Node), v.Counter)
+}
+
+// Context is the clock to write with in order to replace all of the siblings
+func (s Siblings) Context() VectorClock {
+	context := VectorClock{}
+	for _, item := range s {
+		context = context.merge(item.Version.Clock())
+	}
+	return context
+}
+
+// Values of all the siblings
+func (s Siblings) Values() []string {
+	values := []string{}
+	for _, item := range s {
+		values = append(values, string(item.Value))
+	}
+	return values
+}
+
+// The value of the key, with all sibling values listed if there are conflicting versions
+func (s Siblings) String() string {
+	if len(s) == 1 {
+		return string(s[0].Value)
+	}
+	return fmt.Sprintf("%q", s.Values())
+}
+
+// Add versions to the siblings, dropping any version superseded by another.
+// Items that share a version but differ in content are kept side by side, since neither can be said to be newer,
+// unless one is the tombstone of the other.
+func (s Siblings) merge(items ...Item) Siblings {
+	all := append(append(Siblings{}, s...), items...)
+	merged := Siblings{}
+	for i, item := range all {
+		keep := true
+		for j, other := range all {
+			// Drop superseded versions, values whose write has since been deleted, and all but the first copy of each item
+			if other.Version.supersedes(item.Version) ||
+				(other.Version.same(item.Version) && other.deleted() && !item.deleted()) ||
+				(j < i && other.identical(item)) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			merged = append(merged, item)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if a, b := merged[i].Version.String(), merged[j].Version.String(); a != b {
+			return a < b
+		}
+		return bytes.Compare(merged[i].Value, merged[j].Value) < 0
+	})
+	return merged
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("key %s has %d conflicting values: %s", string(e.Key), len(e.Siblings), e.Siblings)
+}
+
+// Refuse a put that no node would store
+func (r PutRequest) check(cfg Config) error {
+	if len(r.Value) > cfg.MaxValueSize {
+		return ErrValueTooLarge
+	}
+	if r.TTL < 0 {
+		return fmt.Errorf("negative TTL %v", r.TTL)
+	}
+	return nil
+}
+
+// Store the new version of a key a put asks for, replacing every version the writer has seen.
+// A nil seen clock overwrites whatever versions the node holds. A zero ttl keeps the item until it is replaced.
+func (n *Node) write(request PutRequest) (Item, error) {
+	item := Item{Value: request.Value, Manifest: request.Manifest}
+	if request.TTL > 0 {
+		item.Expires = time.Now().Add(request.TTL)
+	}
+	return n.store(request.Key, item, request.Seen)
+}
+
+// Store a tombstone for a key accepted by this node, replacing every version it holds
+func (n *Node) delete(key Key) (Item, error) {
+	return n.store(key, Item{Deleted: time.Now()}, nil)
+}
+
+// Give an item the next version of a key accepted by this node and store it, replacing every version in seen
+func (n *Node) store(key Key, item Item, seen VectorClock) (Item, error) {
+	existing, err := n.Data.Get(key)
+	if err != nil {
+		return Item{}, err
+	}
+	if seen == nil {
+		seen = existing.Context()
+	}
+	// Count past every write of the key this node has accepted, whether or not the writer saw it.
+	// Tombstones keep the count of deleted writes.
+	counter := existing.Context().merge(seen)[n.Address] + 1
+	item.Version = Version{n.Address, counter, seen}
+	return item, n.Data.Put(key, existing.merge(item))
+}
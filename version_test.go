@@ -0,0 +1,133 @@
+package chord
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMergeSiblings(t *testing.T) {
+	first := Item{Value: []byte("a"), Version: Version{"n1", 1, VectorClock{}}}
+	second := Item{Value: []byte("b"), Version: Version{"n1", 2, VectorClock{"n1": 1}}}
+	concurrent := Item{Value: []byte("c"), Version: Version{"n2", 1, VectorClock{}}}
+
+	if merged := (Siblings{first}).merge(second); len(merged) != 1 || string(merged[0].Value) != "b" {
+		t.Errorf("a newer version: got %v", merged)
+	}
+	if merged := (Siblings{second}).merge(first); len(merged) != 1 || string(merged[0].Value) != "b" {
+		t.Errorf("an older version: got %v", merged)
+	}
+	if merged := (Siblings{first}).merge(concurrent); len(merged) != 2 {
+		t.Errorf("a concurrent version: got %v", merged)
+	}
+	if merged := (Siblings{first}).merge(first); len(merged) != 1 {
+		t.Errorf("a copy of the same item: got %v", merged)
+	}
+	// A node that lost count of its writes can give two writes the same version
+	reused := Item{Value: []byte("z"), Version: first.Version}
+	if merged := (Siblings{first}).merge(reused); len(merged) != 2 {
+		t.Errorf("the same version with different contents: got %v", merged)
+	}
+	tombstone := Item{Version: first.Version, Deleted: time.Now()}
+	if merged := (Siblings{first}).merge(tombstone); len(merged) != 1 || !merged[0].deleted() {
+		t.Errorf("the tombstone of a write: got %v", merged)
+	}
+	resolved := Item{Value: []byte("d"), Version: Version{"n1", 3, Siblings{first, concurrent}.Context()}}
+	if merged := (Siblings{first, concurrent}).merge(resolved); len(merged) != 1 || string(merged[0].Value) != "d" {
+		t.Errorf("a version that saw both siblings: got %v", merged)
+	}
+}
+
+func TestConflictingVersions(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.Put(ctx, "key", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	read, err := client.GetVersions(ctx, "key", Consistency{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Put(ctx, "key", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	// Written with the context of v1, so it has not seen v2
+	if err := client.PutVersion(ctx, "key", "v3", read.Context(), Consistency{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(ctx, "key"); err == nil {
+		t.Fatal("expected a conflict")
+	} else if conflict, ok := err.(*ConflictError); !ok || len(conflict.Siblings) != 2 {
+		t.Fatalf("expected a conflict between two siblings, got %v", err)
+	}
+
+	siblings, err := client.GetVersions(ctx, "key", Consistency{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PutVersion(ctx, "key", "v4", siblings.Context(), Consistency{}); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := client.Get(ctx, "key"); err != nil || value != "v4" {
+		t.Errorf("after resolving: got %q, %v", value, err)
+	}
+}
+
+func TestWriteAfterMissedDelete(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	replicas := ownerOf(nodes, "key")
+	owner, replica := replicas[0], replicas[2]
+	if err := client.PutWith(ctx, "key", "old", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	network.Partition(owner.Address, replica.Address)
+	if _, err := client.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	network.Heal()
+	if err := client.PutWith(ctx, "key", "new", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The delete kept the owner's count, so the new value supersedes the old one everywhere
+	written := stored(owner, "key")
+	if len(written) != 1 || string(written[0].Value) != "new" || written[0].Version.Counter != 3 {
+		t.Fatalf("owner holds %v", written)
+	}
+	if copied := stored(replica, "key"); !copied.equal(written) {
+		t.Errorf("replica holds %v, owner %v", copied, written)
+	}
+}
+
+func TestDeleteLeavesTombstone(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.PutWith(ctx, "key", "value", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := client.DeleteWith(ctx, "key", Consistency{W: 3}); err != nil || value != "value" {
+		t.Fatalf("delete returned %q, %v", value, err)
+	}
+	for _, n := range nodes {
+		if siblings := stored(n, "key"); len(siblings) != 1 || !siblings[0].deleted() || siblings[0].Version.Counter != 2 {
+			t.Errorf("%s holds %v, expected a tombstone", n.Address, siblings)
+		}
+	}
+	if _, err := client.Get(ctx, "key"); err != ErrNoSuchKey {
+		t.Errorf("get after delete: %v", err)
+	}
+	if _, err := client.Delete(ctx, "key"); err != ErrNoSuchKey {
+		t.Errorf("deleting a deleted key: %v", err)
+	}
+	if err := client.PutIfAbsent(ctx, "key", "again"); err != nil {
+		t.Errorf("put if absent after delete: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package chord
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Watches are long polls. A watcher asks the owner of a key for a change from the versions it last saw, and
+// the owner replies as soon as the stored versions differ, or with no change after a while. Every poll looks the
+// owner up again, so a watch follows the key when ownership moves, and since the poll carries the versions seen
+// rather than a position in a log, the new owner can pick up where the old one left off. A node that does not own
+// the key, or that handed it to another node while the poll waited, replies that it moved rather than that it
+// was deleted, since a delete leaves a tombstone. Writes in quick succession can be reported as one change.
+
+const (
+	watchWait    = 10 * time.Second // How long the owner holds a poll with no change
+	watchBackoff = time.Second      // How long a watcher waits after a failed poll
+)
+
+type (
+	// WatchEvent reports a change to a watched key
+	WatchEvent struct {
+		Key      Key
+		Deleted  bool     // Whether the key was deleted, otherwise it was put
+		Siblings Siblings // The versions now stored, or the versions last seen before a delete
+	}
+
+	// The polls waiting on each key, each woken through its channel
+	watchHub map[Key]map[chan None]bool
+
+	// Storage that wakes the watches of a key whenever it changes
+	watchedStorage struct {
+		Storage
+		node *Node
+	}
+)
+
+// Watch calls handle for every change to a key until the context is done, starting with its current versions if it is stored.
+// Failed polls, such as those to an owner that is leaving, and polls to a node the key has moved from are retried
+// after a pause without reporting a change. The context's error is returned.
+func (c *Client) Watch(ctx context.Context, key Key, handle func(WatchEvent)) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	var last Siblings
+	for {
+		owner, err := c.Lookup(ctx, key)
+		if err == nil {
+			var reply WatchReply
+			request := WatchRequest{key, last.Context(), len(last) > 0, watchWait}
+			if err = c.callFor(ctx, watchWait+c.callTimeout, owner, "NodeActor.Watch", request, &reply); err == nil {
+				if reply.Moved {
+					// Look the owner up again after a pause, since lookups can lag behind the move
+					err = errors.New("key moved")
+				} else {
+					if reply.Changed {
+						event := WatchEvent{key, reply.Deleted, reply.Siblings}
+						if reply.Deleted {
+							event.Siblings, last = last, nil
+						} else {
+							last = reply.Siblings
+						}
+						handle(event)
+					}
+					continue
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchBackoff):
+		}
+	}
+}
+
+// Compare the live versions of a key with the ones a watcher has seen
+func (r WatchRequest) change(live Siblings) WatchReply {
+	if len(live) == 0 {
+		return WatchReply{Changed: r.Exists, Deleted: r.Exists}
+	}
+	for _, item := range live {
+		if !r.Seen.includes(item.Version.Node, item.Version.Counter) {
+			return WatchReply{Changed: true, Siblings: live}
+		}
+	}
+	return WatchReply{}
+}
+
+// Wake every watch of a key
+func (n *Node) notify(key Key) {
+	for changed := range n.watches[key] {
+		select {
+		case changed <- None{}:
+		default:
+		}
+	}
+}
+
+// Hold a watch until the key differs from what the watcher has seen, moves to another node, the wait is over, or the node leaves
+func (n *Node) watch(request WatchRequest, reply *WatchReply) error {
+	changed := make(chan None, 1)
+	check := func(n *Node) error {
+		stored, err := n.Data.Get(request.Key)
+		if err != nil {
+			return err
+		}
+		// A deleted key keeps its tombstone, so a key the watcher saw that is gone altogether was handed off
+		if !n.owns(request.Key) || (request.Exists && stored == nil) {
+			*reply = WatchReply{Moved: true}
+			return nil
+		}
+		*reply = request.change(stored.live(time.Now()))
+		return nil
+	}
+	var err error
+	n.actor.run(func(n *Node) {
+		if n.watches[request.Key] == nil {
+			n.watches[request.Key] = make(map[chan None]bool)
+		}
+		n.watches[request.Key][changed] = true
+		err = check(n)
+	})
+	defer n.actor.run(func(n *Node) {
+		delete(n.watches[request.Key], changed)
+		if len(n.watches[request.Key]) == 0 {
+			delete(n.watches, request.Key)
+		}
+	})
+
+	wait := request.Wait
+	if wait <= 0 || wait > watchWait {
+		wait = watchWait
+	}
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	for err == nil && !reply.Changed && !reply.Moved {
+		select {
+		case <-changed:
+			n.actor.run(func(n *Node) {
+				err = check(n)
+			})
+		case <-timeout.C:
+			return nil
+		case <-n.ctx.Done():
+			return errors.New("node is leaving the ring")
+		}
+	}
+	return err
+}
+
+func (s *watchedStorage) Put(key Key, siblings Siblings) error {
+	err := s.Storage.Put(key, siblings)
+	s.node.notify(key)
+	return err
+}
+
+func (s *watchedStorage) Delete(key Key) error {
+	err := s.Storage.Delete(key)
+	s.node.notify(key)
+	return err
+}
@@ -0,0 +1,124 @@
+package chord
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Watch a key until the test ends, passing on its events
+func watchEvents(t *testing.T, client *Client, key Key) <-chan WatchEvent {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan WatchEvent, 10)
+	done := make(chan None)
+	go func() {
+		defer close(done)
+		client.Watch(ctx, key, func(event WatchEvent) { events <- event })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return events
+}
+
+// The next event of a watch, failing the test if there is none in time
+func nextEvent(t *testing.T, events <-chan WatchEvent) WatchEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(watchWait + 5*time.Second):
+		t.Fatal("timed out waiting for a watch event")
+		return WatchEvent{}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+	if err := client.Put(ctx, "key", "first"); err != nil {
+		t.Fatal(err)
+	}
+	events := watchEvents(t, client, "key")
+	if event := nextEvent(t, events); event.Deleted || event.Siblings.String() != "first" {
+		t.Errorf("first event %v, expected the stored value", event)
+	}
+	if err := client.Put(ctx, "key", "second"); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Deleted || event.Siblings.String() != "second" {
+		t.Errorf("event %v after a put", event)
+	}
+	if _, err := client.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); !event.Deleted || event.Siblings.String() != "second" {
+		t.Errorf("event %v after a delete", event)
+	}
+}
+
+func TestWatchFollowsMovedKey(t *testing.T) {
+	network := NewMemoryNetwork()
+	// Without replicas the old owner drops the key as soon as it hands it to a node that joins
+	oneCopy := func(cfg *Config) { cfg.Replicas = 1 }
+	nodes := testRing(t, network, 2, oneCopy)
+	client := testClient(t, network, nodes)
+	ctx := context.Background()
+
+	// A key the joining node will own
+	joining := &Node{Address: "node2", Hash: Address("node2").hashed()}
+	var key Key
+	for i := 0; ownerOf(append(nodes, joining), key)[0] != joining; i++ {
+		key = Key(fmt.Sprint("key", i))
+	}
+	if err := client.Put(ctx, key, "before"); err != nil {
+		t.Fatal(err)
+	}
+	events := watchEvents(t, client, key)
+	if event := nextEvent(t, events); event.Siblings.String() != "before" {
+		t.Fatalf("first event %v, expected the stored value", event)
+	}
+
+	cfg := testConfig(network, joining.Address)
+	oneCopy(&cfg)
+	joined, err := Join(cfg, nodes[0].Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { joined.stopNode() })
+	nodes = append(nodes, joined)
+	waitForRing(t, nodes)
+	eventually(t, "the key to move to the joined node", func() bool {
+		return holds(joined, key, "before")
+	})
+
+	// The move is not reported, and the watch goes on at the new owner
+	if err := client.Put(ctx, key, "after"); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Deleted || event.Siblings.String() != "after" {
+		t.Errorf("event %v after the key moved and was put, expected the new value", event)
+	}
+}
+
+func TestWatchAtOtherNode(t *testing.T) {
+	network := NewMemoryNetwork()
+	nodes := testRing(t, network, 3, nil)
+	client := testClient(t, network, nodes)
+	if err := client.PutWith(context.Background(), "key", "v", Consistency{W: 3}); err != nil {
+		t.Fatal(err)
+	}
+	// A replica holds the key but does not own it
+	replica := ownerOf(nodes, "key")[1]
+	var reply WatchReply
+	if err := replica.watch(WatchRequest{Key: "key", Wait: time.Second}, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Moved || reply.Changed {
+		t.Errorf("watch at a replica replied %+v, expected the key to have moved", reply)
+	}
+}